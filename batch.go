@@ -0,0 +1,96 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// BatchOptions configure batch validation.
+type BatchOptions struct {
+	// Workers is the number of segments validated concurrently.
+	// Defaults to the number of CPUs.
+	Workers int
+}
+
+// ValidateBatch validates segments concurrently.
+// It returns the result of Segment.Validate for each segment, in the same
+// order as the input. Signatures that appear in multiple segments (same
+// payload, key and signature bytes) are only verified once: the cache of
+// verifications is sized to hold every signature of the batch.
+// If the context is cancelled, segments that weren't validated get the
+// context's error and it is also returned as the second value.
+func ValidateBatch(ctx context.Context, segments []*Segment, opts *BatchOptions) ([]error, error) {
+	workers := runtime.NumCPU()
+	if opts != nil && opts.Workers > 0 {
+		workers = opts.Workers
+	}
+
+	ctx = withSignatureCache(ctx, newSignatureCache(countSignatures(segments)))
+	results := make([]error, len(segments))
+	validated := make([]bool, len(segments))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = segments[i].Validate(ctx)
+				validated[i] = true
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for i := range segments {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	if err != nil {
+		for i := range results {
+			if !validated[i] {
+				results[i] = err
+			}
+		}
+
+		return results, err
+	}
+
+	return results, nil
+}
+
+// countSignatures returns the number of link signatures of the segments.
+func countSignatures(segments []*Segment) int {
+	count := 0
+	for _, s := range segments {
+		count += len(s.GetLink().GetSignatures())
+	}
+
+	return count
+}
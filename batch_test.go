@@ -0,0 +1,115 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBatch(t *testing.T) {
+	t.Run("empty batch", func(t *testing.T) {
+		results, err := chainscript.ValidateBatch(context.Background(), nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("per-segment results", func(t *testing.T) {
+		key := chainscripttest.RandomPrivateKey(t)
+		signed := chainscripttest.NewLinkBuilder(t).WithSignatureFromKey(t, key, "[version,meta.mapId]").Build()
+
+		// The same signature is valid on links that share the signed payload.
+		sameSignature := chainscripttest.NewLinkBuilder(t).WithAction("other").Build()
+		sameSignature.Signatures = signed.Signatures
+
+		invalidSignature := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").WithInvalidSignature(t).Build()
+
+		segments := []*chainscript.Segment{
+			chainscripttest.RandomSegment(t),
+			chainscripttest.NewLinkBuilder(t).WithMapID("").Segmentify(t),
+			chainscripttest.NewLinkBuilder(t).From(t, signed).Segmentify(t),
+			chainscripttest.NewLinkBuilder(t).From(t, sameSignature).Segmentify(t),
+			chainscripttest.NewLinkBuilder(t).From(t, invalidSignature).Segmentify(t),
+			chainscripttest.NewLinkBuilder(t).From(t, invalidSignature).Segmentify(t),
+		}
+
+		results, err := chainscript.ValidateBatch(context.Background(), segments, &chainscript.BatchOptions{Workers: 2})
+		require.NoError(t, err)
+		require.Len(t, results, len(segments))
+
+		assert.NoError(t, results[0])
+		assert.EqualError(t, errors.Cause(results[1]), chainscript.ErrMissingMapID.Error())
+		assert.NoError(t, results[2])
+		assert.NoError(t, results[3])
+		assert.EqualError(t, errors.Cause(results[4]), chainscript.ErrInvalidSignature.Error())
+		assert.EqualError(t, errors.Cause(results[5]), chainscript.ErrInvalidSignature.Error())
+	})
+
+	t.Run("same results as serial validation", func(t *testing.T) {
+		var segments []*chainscript.Segment
+		for i := 0; i < 50; i++ {
+			lb := chainscripttest.NewLinkBuilder(t).WithRandomData().WithSignature(t, "")
+			if i%7 == 0 {
+				lb = lb.WithInvalidSignature(t)
+			}
+			if i%5 == 0 {
+				lb = lb.WithInvalidFields()
+			}
+
+			segments = append(segments, lb.Segmentify(t))
+		}
+
+		results, err := chainscript.ValidateBatch(context.Background(), segments, nil)
+		require.NoError(t, err)
+
+		for i, s := range segments {
+			if expected := s.Validate(context.Background()); expected != nil {
+				assert.EqualError(t, results[i], expected.Error(), "segment %d", i)
+			} else {
+				assert.NoError(t, results[i], "segment %d", i)
+			}
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		segments := []*chainscript.Segment{
+			chainscripttest.RandomSegment(t),
+			chainscripttest.RandomSegment(t),
+		}
+
+		results, err := chainscript.ValidateBatch(ctx, segments, &chainscript.BatchOptions{Workers: 1})
+		assert.EqualError(t, err, context.Canceled.Error())
+		require.Len(t, results, 2)
+
+		cancelled := 0
+		for _, res := range results {
+			if res == context.Canceled {
+				cancelled++
+			} else {
+				assert.NoError(t, res)
+			}
+		}
+		assert.True(t, cancelled > 0)
+	})
+}
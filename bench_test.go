@@ -0,0 +1,262 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run the benchmarks with go test -run XXX -bench . -benchmem.
+
+// dataSizes are the approximate sizes (in bytes) of the benchmarked link
+// data.
+var dataSizes = []int{64, 1024, 64 * 1024}
+
+// signatureCounts are the numbers of signatures of the benchmarked links.
+var signatureCounts = []int{0, 1, 4}
+
+// benchData returns JSON data of approximately the given size.
+func benchData(size int) map[string]interface{} {
+	data := make(map[string]interface{})
+	for i := 0; i < size/32; i++ {
+		data[fmt.Sprintf("field-%04d", i)] = chainscripttest.RandomString(16)
+	}
+
+	return data
+}
+
+// benchSegment creates a segment with the given data size, signature count
+// and two evidences.
+func benchSegment(b testing.TB, size, signatures int) *chainscript.Segment {
+	lb := chainscripttest.NewLinkBuilder(b).
+		WithData(b, benchData(size)).
+		WithMetadata(b, map[string]interface{}{"source": "benchmark"}).
+		WithTags("tag1", "tag2").
+		WithRef(b, chainscripttest.RandomLink(b))
+
+	for i := 0; i < signatures; i++ {
+		lb = lb.WithSignature(b, "")
+	}
+
+	s := lb.Segmentify(b)
+	require.NoError(b, s.AddEvidence(chainscripttest.RandomEvidence(b)))
+	require.NoError(b, s.AddEvidence(chainscripttest.RandomEvidence(b)))
+
+	return s
+}
+
+func BenchmarkLink_Hash(b *testing.B) {
+	for _, size := range dataSizes {
+		b.Run(fmt.Sprintf("data=%d", size), func(b *testing.B) {
+			l := benchSegment(b, size, 1).Link
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := l.Hash(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLink_SetData(b *testing.B) {
+	for _, size := range dataSizes {
+		b.Run(fmt.Sprintf("data=%d", size), func(b *testing.B) {
+			l := chainscripttest.NewLinkBuilder(b).Build()
+			data := benchData(size)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := l.SetData(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLink_Sign(b *testing.B) {
+	for _, size := range dataSizes {
+		b.Run(fmt.Sprintf("data=%d", size), func(b *testing.B) {
+			l := benchSegment(b, size, 0).Link
+			key := chainscripttest.RandomPrivateKey(b)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Signatures = nil
+				if err := l.Sign(key, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSignature_Validate(b *testing.B) {
+	for _, size := range dataSizes {
+		b.Run(fmt.Sprintf("data=%d", size), func(b *testing.B) {
+			l := benchSegment(b, size, 1).Link
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := l.Signatures[0].Validate(l); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSegment_Validate(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range dataSizes {
+		for _, signatures := range signatureCounts {
+			b.Run(fmt.Sprintf("data=%d/signatures=%d", size, signatures), func(b *testing.B) {
+				s := benchSegment(b, size, signatures)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := s.Validate(ctx); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkMarshalSegment(b *testing.B) {
+	for _, size := range dataSizes {
+		b.Run(fmt.Sprintf("data=%d", size), func(b *testing.B) {
+			s := benchSegment(b, size, 1)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := chainscript.MarshalSegment(s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalSegment(b *testing.B) {
+	for _, size := range dataSizes {
+		b.Run(fmt.Sprintf("data=%d", size), func(b *testing.B) {
+			data, err := chainscript.MarshalSegment(benchSegment(b, size, 1))
+			require.NoError(b, err)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := chainscript.UnmarshalSegment(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// raceEnabled is set when the race detector is on (see race_test.go): it
+// adds allocations, so allocation budgets aren't checked.
+var raceEnabled bool
+
+// TestAllocationBudgets checks the number of allocations of the benchmarked
+// functions (with 1KB of data and one signature) so that regressions are
+// caught before they ship.
+// Allocation counts depend on the Go version and dependencies, so budgets
+// are only checked when CHAINSCRIPT_ALLOC_BUDGETS is set (see make allocs).
+func TestAllocationBudgets(t *testing.T) {
+	if os.Getenv("CHAINSCRIPT_ALLOC_BUDGETS") == "" {
+		t.Skip("set CHAINSCRIPT_ALLOC_BUDGETS to check allocation budgets")
+	}
+	if raceEnabled {
+		t.Skip("allocations are not representative with the race detector")
+	}
+
+	ctx := context.Background()
+	s := benchSegment(t, 1024, 1)
+	unsigned := benchSegment(t, 1024, 0)
+	data := benchData(1024)
+	key := chainscripttest.RandomPrivateKey(t)
+
+	segmentBytes, err := chainscript.MarshalSegment(s)
+	require.NoError(t, err)
+
+	budgets := []struct {
+		name   string
+		budget float64
+		run    func() error
+	}{{
+		"Link.Hash",
+		20,
+		func() error { _, err := s.Link.Hash(); return err },
+	}, {
+		"Link.SetData",
+		110,
+		func() error { return unsigned.Link.SetData(data) },
+	}, {
+		"Link.Sign",
+		120,
+		func() error {
+			unsigned.Link.Signatures = nil
+			return unsigned.Link.Sign(key, "")
+		},
+	}, {
+		"Signature.Validate",
+		60,
+		func() error { return s.Link.Signatures[0].Validate(s.Link) },
+	}, {
+		"Segment.Validate",
+		90,
+		func() error { return s.Validate(ctx) },
+	}, {
+		"MarshalSegment",
+		40,
+		func() error { _, err := chainscript.MarshalSegment(s); return err },
+	}, {
+		"UnmarshalSegment",
+		70,
+		func() error { _, err := chainscript.UnmarshalSegment(segmentBytes); return err },
+	}}
+
+	for _, b := range budgets {
+		t.Run(b.name, func(t *testing.T) {
+			require.NoError(t, b.run())
+
+			allocs := testing.AllocsPerRun(20, func() {
+				if err := b.run(); err != nil {
+					t.Fatal(err)
+				}
+			})
+			assert.True(t, allocs <= b.budget, "%s: %.0f allocations (budget: %.0f)", b.name, allocs, b.budget)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Certificate errors.
+var (
+	ErrMissingCertificate        = errors.New("signature doesn't contain a certificate")
+	ErrInvalidCertificate        = errors.New("certificate chain is invalid")
+	ErrUntrustedCertificate      = errors.New("certificate chain isn't trusted")
+	ErrInvalidKeyUsage           = errors.New("certificate can't be used for code signing")
+	ErrUnsupportedCertificateKey = errors.New("certificate key type is not supported")
+	ErrCertificateKeyMismatch    = errors.New("signer doesn't match the certificate's public key")
+)
+
+// SignWithCertificate signs configurable parts of the link with the
+// certificate-based signature version (SignatureVersion1_1_0).
+// The chain starts with the signer's certificate and can contain
+// intermediate certificates. The signer must hold the certificate's private
+// key (RSA or ECDSA). If no payloadPath is provided, the whole link is
+// signed.
+func (l *Link) SignWithCertificate(signer crypto.Signer, chain []*x509.Certificate, payloadPath string) error {
+	if len(chain) == 0 {
+		return ErrMissingCertificate
+	}
+
+	if _, err := certificateSignatureAlgorithm(chain[0]); err != nil {
+		return err
+	}
+
+	signerKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !bytes.Equal(signerKey, chain[0].RawSubjectPublicKeyInfo) {
+		return ErrCertificateKeyMismatch
+	}
+
+	if len(payloadPath) == 0 {
+		payloadPath = "[version,data,meta]"
+	}
+
+	payload, err := l.SignedBytes(SignatureVersion1_1_0, payloadPath)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(payload)
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var publicKey []byte
+	for _, cert := range chain {
+		publicKey = append(publicKey, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	l.Signatures = append(l.Signatures, &Signature{
+		Version:     SignatureVersion1_1_0,
+		PayloadPath: payloadPath,
+		PublicKey:   publicKey,
+		Signature:   sig,
+	})
+
+	return nil
+}
+
+// Signer returns the certificate of a certificate-based signature.
+// Its subject identifies the signer.
+// The certificate chain isn't verified, use Link.Validate for that.
+func (s *Signature) Signer() (*x509.Certificate, error) {
+	if s.Version != SignatureVersion1_1_0 {
+		return nil, ErrMissingCertificate
+	}
+
+	chain, err := parseCertificateChain(s.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return chain[0], nil
+}
+
+// WithCertificateRoots returns a context containing the root certificates
+// used to verify certificate-based signatures.
+// Without roots in the context, certificate-based signatures are invalid.
+func WithCertificateRoots(ctx context.Context, roots *x509.CertPool) context.Context {
+	return context.WithValue(ctx, certificateRootsKey, roots)
+}
+
+// CertificateRootsFromContext returns the root certificates stored in the
+// context, or nil if there are none.
+func CertificateRootsFromContext(ctx context.Context) *x509.CertPool {
+	roots, _ := ctx.Value(certificateRootsKey).(*x509.CertPool)
+	return roots
+}
+
+// parseCertificateChain parses PEM-encoded or concatenated DER certificates.
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+
+	if block, rest := pem.Decode(data); block != nil {
+		for ; block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(ErrInvalidCertificate, err.Error())
+			}
+
+			chain = append(chain, cert)
+		}
+	} else {
+		certs, err := x509.ParseCertificates(data)
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidCertificate, err.Error())
+		}
+
+		chain = certs
+	}
+
+	if len(chain) == 0 {
+		return nil, ErrMissingCertificate
+	}
+
+	return chain, nil
+}
+
+// verifyCertificateChain builds a path from the signer's certificate to the
+// roots stored in the context and checks that it can be used for code
+// signing at the given time (the current time if zero, see WithClock).
+// The signer's certificate must explicitly allow digital signatures (or
+// content commitment) and code signing.
+func verifyCertificateChain(ctx context.Context, chain []*x509.Certificate, at time.Time) error {
+	roots := CertificateRootsFromContext(ctx)
+	if roots == nil {
+		return errors.Wrap(ErrUntrustedCertificate, "no certificate roots")
+	}
+
+	leaf := chain[0]
+	if leaf.KeyUsage&(x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment) == 0 {
+		return ErrInvalidKeyUsage
+	}
+
+	if !hasExtKeyUsage(leaf, x509.ExtKeyUsageCodeSigning) {
+		return ErrInvalidKeyUsage
+	}
+
+	if at.IsZero() {
+		at = now(ctx)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return errors.Wrap(ErrUntrustedCertificate, err.Error())
+	}
+
+	return nil
+}
+
+// hasExtKeyUsage checks that the certificate explicitly lists the extended
+// key usage.
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyCertificateSignature checks the signature of the signed bytes with the
+// certificate's public key.
+func verifyCertificateSignature(cert *x509.Certificate, signedBytes, sig []byte) error {
+	algorithm, err := certificateSignatureAlgorithm(cert)
+	if err != nil {
+		return err
+	}
+
+	if err := cert.CheckSignature(algorithm, signedBytes, sig); err != nil {
+		return errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+
+	return nil
+}
+
+// certificateSignatureAlgorithm returns the algorithm used to sign links with
+// the certificate's key.
+func certificateSignatureAlgorithm(cert *x509.Certificate) (x509.SignatureAlgorithm, error) {
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA, nil
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256, nil
+	default:
+		return x509.UnknownSignatureAlgorithm, ErrUnsupportedCertificateKey
+	}
+}
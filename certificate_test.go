@@ -0,0 +1,277 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a certificate authority generated for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	return &testCA{cert: createCertificate(t, template, template, key, key), key: key}
+}
+
+// issue creates a certificate signed by the CA.
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate, key crypto.Signer) *x509.Certificate {
+	return createCertificate(t, template, ca.cert, key, ca.key)
+}
+
+// intermediate creates an intermediate CA signed by the CA.
+func (ca *testCA) intermediate(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	return &testCA{cert: ca.issue(t, template, key), key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, key, parentKey crypto.Signer) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func signerTemplate(usage x509.KeyUsage) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "alice", Organization: []string{"Stratumn"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     usage,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+}
+
+func TestLink_SignWithCertificate(t *testing.T) {
+	ca := newTestCA(t, "root")
+	intermediate := ca.intermediate(t, "intermediate")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecCert := intermediate.issue(t, signerTemplate(x509.KeyUsageDigitalSignature), ecKey)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaCert := ca.issue(t, signerTemplate(x509.KeyUsageContentCommitment), rsaKey)
+
+	ctx := chainscript.WithCertificateRoots(context.Background(), ca.pool())
+
+	t.Run("ecdsa with intermediate", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithRandomData().Build()
+		require.NoError(t, l.SignWithCertificate(ecKey, []*x509.Certificate{ecCert, intermediate.cert}, ""))
+
+		require.Len(t, l.Signatures, 1)
+		assert.Equal(t, chainscript.SignatureVersion1_1_0, l.Signatures[0].Version)
+		assert.Equal(t, "[version,data,meta]", l.Signatures[0].PayloadPath)
+		assert.NoError(t, l.Validate(ctx))
+
+		signer, err := l.Signatures[0].Signer()
+		require.NoError(t, err)
+		assert.Equal(t, "alice", signer.Subject.CommonName)
+		assert.Equal(t, []string{"Stratumn"}, signer.Subject.Organization)
+	})
+
+	t.Run("rsa", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithRandomData().Build()
+		require.NoError(t, l.SignWithCertificate(rsaKey, []*x509.Certificate{rsaCert}, "[data]"))
+		assert.NoError(t, l.Validate(ctx))
+	})
+
+	t.Run("der chain", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(ecKey, []*x509.Certificate{ecCert, intermediate.cert}, ""))
+		l.Signatures[0].PublicKey = append(append([]byte{}, ecCert.Raw...), intermediate.cert.Raw...)
+
+		assert.NoError(t, l.Validate(ctx))
+	})
+
+	t.Run("missing certificate", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		err := l.SignWithCertificate(ecKey, nil, "")
+		assert.EqualError(t, err, chainscript.ErrMissingCertificate.Error())
+	})
+
+	t.Run("key mismatch", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		err := l.SignWithCertificate(rsaKey, []*x509.Certificate{ecCert}, "")
+		assert.EqualError(t, err, chainscript.ErrCertificateKeyMismatch.Error())
+		assert.Equal(t, chainscript.CodeCertificateKeyMismatch, chainscript.ErrorCode(err))
+		assert.Empty(t, l.Signatures)
+	})
+
+	t.Run("tampered link", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(ecKey, []*x509.Certificate{ecCert, intermediate.cert}, ""))
+		l.Meta.Action = "tampered"
+
+		err := l.Validate(ctx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrInvalidSignature.Error())
+	})
+
+	t.Run("missing intermediate", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(ecKey, []*x509.Certificate{ecCert}, ""))
+
+		err := l.Validate(ctx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUntrustedCertificate.Error())
+	})
+
+	t.Run("unknown root", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(rsaKey, []*x509.Certificate{rsaCert}, ""))
+
+		otherCtx := chainscript.WithCertificateRoots(context.Background(), newTestCA(t, "other").pool())
+		err := l.Validate(otherCtx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUntrustedCertificate.Error())
+	})
+
+	t.Run("no roots", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(rsaKey, []*x509.Certificate{rsaCert}, ""))
+
+		err := l.Validate(context.Background())
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUntrustedCertificate.Error())
+	})
+
+	invalidUsages := []struct {
+		name     string
+		template func() *x509.Certificate
+	}{{
+		"key encipherment",
+		func() *x509.Certificate { return signerTemplate(x509.KeyUsageKeyEncipherment) },
+	}, {
+		"no key usage",
+		func() *x509.Certificate { return signerTemplate(0) },
+	}, {
+		"no extended key usage",
+		func() *x509.Certificate {
+			template := signerTemplate(x509.KeyUsageDigitalSignature)
+			template.ExtKeyUsage = nil
+			return template
+		},
+	}, {
+		"any extended key usage",
+		func() *x509.Certificate {
+			template := signerTemplate(x509.KeyUsageDigitalSignature)
+			template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+			return template
+		},
+	}}
+
+	for _, tt := range invalidUsages {
+		t.Run("invalid key usage/"+tt.name, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(t, err)
+			cert := ca.issue(t, tt.template(), key)
+
+			l := chainscripttest.NewLinkBuilder(t).Build()
+			require.NoError(t, l.SignWithCertificate(key, []*x509.Certificate{cert}, ""))
+
+			err = l.Validate(ctx)
+			assert.EqualError(t, errors.Cause(err), chainscript.ErrInvalidKeyUsage.Error())
+			assert.Equal(t, chainscript.CodeInvalidKeyUsage, chainscript.ErrorCode(err))
+		})
+	}
+
+	t.Run("trust store", func(t *testing.T) {
+		// Certificate signatures are trusted through the roots, not the
+		// trust store.
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(rsaKey, []*x509.Certificate{rsaCert}, ""))
+
+		trustCtx := chainscript.WithTrustStore(ctx, chainscript.NewMemoryTrustStore())
+		assert.NoError(t, l.Validate(trustCtx))
+	})
+
+	t.Run("not valid at evidence time", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.SignWithCertificate(rsaKey, []*x509.Certificate{rsaCert}, ""))
+
+		s, err := l.Segmentify()
+		require.NoError(t, err)
+		require.NoError(t, s.AddEvidence(timeEvidence(time.Now().Add(-24*time.Hour))))
+
+		err = s.Validate(withTimeEvidenceTrust(ctx))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUntrustedCertificate.Error())
+		assert.NoError(t, l.Validate(ctx))
+	})
+}
+
+func TestSignature_Signer(t *testing.T) {
+	t.Run("key signature", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").Build()
+		_, err := l.Signatures[0].Signer()
+		assert.EqualError(t, err, chainscript.ErrMissingCertificate.Error())
+	})
+
+	t.Run("invalid certificate", func(t *testing.T) {
+		s := &chainscript.Signature{Version: chainscript.SignatureVersion1_1_0, PublicKey: []byte("not a certificate")}
+		_, err := s.Signer()
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrInvalidCertificate.Error())
+	})
+}
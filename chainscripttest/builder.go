@@ -15,7 +15,6 @@
 package chainscripttest
 
 import (
-	"math/rand"
 	"testing"
 
 	"github.com/stratumn/go-chainscript"
@@ -28,7 +27,7 @@ type LinkBuilder struct {
 }
 
 // NewLinkBuilder creates a new LinkBuilder.
-func NewLinkBuilder(t *testing.T) *LinkBuilder {
+func NewLinkBuilder(t testing.TB) *LinkBuilder {
 	l, err := chainscript.NewLinkBuilder("process", "mapID").Build()
 	require.NoError(t, err)
 
@@ -36,7 +35,7 @@ func NewLinkBuilder(t *testing.T) *LinkBuilder {
 }
 
 // Branch uses the provided link as its parent and copies its mapID and process.
-func (lb *LinkBuilder) Branch(t *testing.T, parent *chainscript.Link) *LinkBuilder {
+func (lb *LinkBuilder) Branch(t testing.TB, parent *chainscript.Link) *LinkBuilder {
 	lh, err := parent.Hash()
 	require.NoError(t, err)
 
@@ -51,7 +50,7 @@ func (lb *LinkBuilder) Branch(t *testing.T, parent *chainscript.Link) *LinkBuild
 }
 
 // From clones the given link.
-func (lb *LinkBuilder) From(t *testing.T, l *chainscript.Link) *LinkBuilder {
+func (lb *LinkBuilder) From(t testing.TB, l *chainscript.Link) *LinkBuilder {
 	var err error
 	lb.Link, err = l.Clone()
 	require.NoError(t, err)
@@ -72,7 +71,7 @@ func (lb *LinkBuilder) WithClientID(clientID string) *LinkBuilder {
 }
 
 // WithData fills the link's data.
-func (lb *LinkBuilder) WithData(t *testing.T, data interface{}) *LinkBuilder {
+func (lb *LinkBuilder) WithData(t testing.TB, data interface{}) *LinkBuilder {
 	err := lb.Link.SetData(data)
 	require.NoError(t, err)
 
@@ -94,7 +93,7 @@ func (lb *LinkBuilder) WithInvalidFields() *LinkBuilder {
 }
 
 // WithInvalidSignature adds an invalid signature to the link.
-func (lb *LinkBuilder) WithInvalidSignature(t *testing.T) *LinkBuilder {
+func (lb *LinkBuilder) WithInvalidSignature(t testing.TB) *LinkBuilder {
 	err := lb.Link.Sign(RandomPrivateKey(t), "")
 	require.NoError(t, err)
 
@@ -109,7 +108,7 @@ func (lb *LinkBuilder) WithMapID(mapID string) *LinkBuilder {
 }
 
 // WithMetadata sets the link meta.Data field.
-func (lb *LinkBuilder) WithMetadata(t *testing.T, metadata interface{}) *LinkBuilder {
+func (lb *LinkBuilder) WithMetadata(t testing.TB, metadata interface{}) *LinkBuilder {
 	err := lb.Link.SetMetadata(metadata)
 	require.NoError(t, err)
 
@@ -123,7 +122,7 @@ func (lb *LinkBuilder) WithoutParent() *LinkBuilder {
 }
 
 // WithParent fills the link's prevLinkHash with the given parent's hash.
-func (lb *LinkBuilder) WithParent(t *testing.T, link *chainscript.Link) *LinkBuilder {
+func (lb *LinkBuilder) WithParent(t testing.TB, link *chainscript.Link) *LinkBuilder {
 	linkHash, err := link.Hash()
 	require.NoError(t, err)
 
@@ -169,7 +168,7 @@ func (lb *LinkBuilder) WithRandomData() *LinkBuilder {
 	lb.Link.Meta.Action = RandomString(12)
 	lb.Link.Meta.Data = RandomBytes(24)
 	lb.Link.Meta.MapId = RandomString(24)
-	lb.Link.Meta.Priority = rand.Float64()
+	lb.Link.Meta.Priority = source.r.Float64()
 	lb.Link.Meta.Process = &chainscript.Process{
 		Name:  RandomString(24),
 		State: RandomString(24),
@@ -181,7 +180,7 @@ func (lb *LinkBuilder) WithRandomData() *LinkBuilder {
 }
 
 // WithRef adds a reference to the link.
-func (lb *LinkBuilder) WithRef(t *testing.T, link *chainscript.Link) *LinkBuilder {
+func (lb *LinkBuilder) WithRef(t testing.TB, link *chainscript.Link) *LinkBuilder {
 	require.NotNil(t, link.Meta.Process)
 
 	refHash, err := link.Hash()
@@ -197,7 +196,7 @@ func (lb *LinkBuilder) WithRef(t *testing.T, link *chainscript.Link) *LinkBuilde
 
 // WithSignature signs the link with a random key.
 // Provide an empty payload to sign the whole link.
-func (lb *LinkBuilder) WithSignature(t *testing.T, payloadPath string) *LinkBuilder {
+func (lb *LinkBuilder) WithSignature(t testing.TB, payloadPath string) *LinkBuilder {
 	err := lb.Link.Sign(RandomPrivateKey(t), payloadPath)
 	require.NoError(t, err)
 
@@ -206,7 +205,7 @@ func (lb *LinkBuilder) WithSignature(t *testing.T, payloadPath string) *LinkBuil
 
 // WithSignatureFromKey signs the link with the given key.
 // Provide an empty payload to sign the whole link.
-func (lb *LinkBuilder) WithSignatureFromKey(t *testing.T, key []byte, payloadPath string) *LinkBuilder {
+func (lb *LinkBuilder) WithSignatureFromKey(t testing.TB, key []byte, payloadPath string) *LinkBuilder {
 	err := lb.Link.Sign(key, payloadPath)
 	require.NoError(t, err)
 
@@ -243,7 +242,7 @@ func (lb *LinkBuilder) Build() *chainscript.Link {
 }
 
 // Segmentify builds the link and then segmentifies it.
-func (lb *LinkBuilder) Segmentify(t *testing.T) *chainscript.Segment {
+func (lb *LinkBuilder) Segmentify(t testing.TB) *chainscript.Segment {
 	s, err := lb.Build().Segmentify()
 	require.NoError(t, err)
 
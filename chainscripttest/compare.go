@@ -15,6 +15,7 @@
 package chainscripttest
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stratumn/go-chainscript"
@@ -24,20 +25,17 @@ import (
 // LinksEqual compares two links.
 // We can't directly compare the structs because protobuf sets some internal
 // state data in the XXX_* fields of each underlying struct when serializing.
-func LinksEqual(t *testing.T, l1, l2 *chainscript.Link) {
-	lh1, err := l1.Hash()
-	require.NoError(t, err)
-
-	lh2, err := l2.Hash()
-	require.NoError(t, err)
-
-	require.Equal(t, lh1, lh2)
+// On failure, it prints the differences field by field (see DiffLinks).
+func LinksEqual(t testing.TB, l1, l2 *chainscript.Link, opts ...CompareOption) {
+	if diffs := DiffLinks(l1, l2, opts...); len(diffs) > 0 {
+		require.FailNow(t, "links are different", strings.Join(diffs, "\n"))
+	}
 }
 
 // EvidencesEqual compares two evidences.
 // We can't directly compare the structs because protobuf sets some internal
 // state data in the XXX_* fields of each underlying struct when serializing.
-func EvidencesEqual(t *testing.T, e1, e2 *chainscript.Evidence) {
+func EvidencesEqual(t testing.TB, e1, e2 *chainscript.Evidence) {
 	require.Equal(t, e1.Version, e2.Version)
 	require.Equal(t, e1.Backend, e2.Backend)
 	require.Equal(t, e1.Provider, e2.Provider)
@@ -47,12 +45,9 @@ func EvidencesEqual(t *testing.T, e1, e2 *chainscript.Evidence) {
 // SegmentsEqual compares two segments.
 // We can't directly compare the structs because protobuf sets some internal
 // state data in the XXX_* fields of each underlying struct when serializing.
-func SegmentsEqual(t *testing.T, s1, s2 *chainscript.Segment) {
-	LinksEqual(t, s1.Link, s2.Link)
-	require.Equal(t, s1.LinkHash(), s2.LinkHash())
-
-	require.Equal(t, len(s1.Meta.Evidences), len(s2.Meta.Evidences))
-	for i := 0; i < len(s1.Meta.Evidences); i++ {
-		EvidencesEqual(t, s1.Meta.Evidences[i], s2.Meta.Evidences[i])
+// On failure, it prints the differences field by field (see DiffSegments).
+func SegmentsEqual(t testing.TB, s1, s2 *chainscript.Segment, opts ...CompareOption) {
+	if diffs := DiffSegments(s1, s2, opts...); len(diffs) > 0 {
+		require.FailNow(t, "segments are different", strings.Join(diffs, "\n"))
 	}
 }
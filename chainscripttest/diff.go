@@ -0,0 +1,274 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscripttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/stratumn/go-chainscript"
+)
+
+// CompareOption relaxes the comparison of links and segments.
+type CompareOption func(*compareOptions)
+
+type compareOptions struct {
+	evidenceOrder  bool
+	tagOrder       bool
+	signatureOrder bool
+}
+
+// IgnoreEvidenceOrder compares segment evidences as a set.
+func IgnoreEvidenceOrder() CompareOption {
+	return func(o *compareOptions) { o.evidenceOrder = true }
+}
+
+// IgnoreTagOrder compares link tags as a set.
+func IgnoreTagOrder() CompareOption {
+	return func(o *compareOptions) { o.tagOrder = true }
+}
+
+// IgnoreSignatureOrder compares link signatures as a set.
+func IgnoreSignatureOrder() CompareOption {
+	return func(o *compareOptions) { o.signatureOrder = true }
+}
+
+func newCompareOptions(opts []CompareOption) *compareOptions {
+	o := &compareOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// relaxed returns true if some fields are compared regardless of order, in
+// which case links with different hashes can be considered equal.
+func (o *compareOptions) relaxed() bool {
+	return o.evidenceOrder || o.tagOrder || o.signatureOrder
+}
+
+// DiffLinks returns the differences between two links, one line per field.
+// Link data and metadata are decoded to show readable differences.
+func DiffLinks(l1, l2 *chainscript.Link, opts ...CompareOption) []string {
+	o := newCompareOptions(opts)
+	diffs := diff("link", linkView(l1, o), linkView(l2, o), nil)
+	if len(diffs) == 0 && !o.relaxed() {
+		diffs = diffHashes(l1, l2)
+	}
+
+	return diffs
+}
+
+// DiffSegments returns the differences between two segments, one line per
+// field. Link data and metadata are decoded to show readable differences.
+func DiffSegments(s1, s2 *chainscript.Segment, opts ...CompareOption) []string {
+	o := newCompareOptions(opts)
+	diffs := diff("segment", segmentView(s1, o), segmentView(s2, o), nil)
+	if len(diffs) == 0 && !o.relaxed() && s1 != nil && s2 != nil {
+		diffs = diffHashes(s1.Link, s2.Link)
+	}
+
+	return diffs
+}
+
+// diffHashes catches differences that aren't visible in the JSON
+// representation of the links (such as unknown protobuf fields).
+func diffHashes(l1, l2 *chainscript.Link) []string {
+	if l1 == nil || l2 == nil {
+		return nil
+	}
+
+	lh1, err1 := l1.Hash()
+	lh2, err2 := l2.Hash()
+	if err1 != nil || err2 != nil || !reflect.DeepEqual(lh1, lh2) {
+		return []string{fmt.Sprintf("link hash: %s != %s (fields not visible in JSON differ)", lh1, lh2)}
+	}
+
+	return nil
+}
+
+// segmentView returns the JSON representation of the segment, normalized
+// according to the options.
+func segmentView(s *chainscript.Segment, o *compareOptions) interface{} {
+	if s == nil {
+		return nil
+	}
+
+	view, _ := toJSON(s).(map[string]interface{})
+	if view == nil {
+		return nil
+	}
+
+	if s.Link != nil {
+		view["link"] = linkView(s.Link, o)
+	}
+
+	if meta, _ := view["meta"].(map[string]interface{}); meta != nil && o.evidenceOrder {
+		sortJSON(meta["evidences"])
+	}
+
+	return view
+}
+
+// linkView returns the JSON representation of the link with its data and
+// metadata decoded, normalized according to the options.
+func linkView(l *chainscript.Link, o *compareOptions) interface{} {
+	if l == nil {
+		return nil
+	}
+
+	view, _ := toJSON(l).(map[string]interface{})
+	if view == nil {
+		return nil
+	}
+
+	if len(l.Data) > 0 {
+		view["data"] = decodeData(l.Data)
+	}
+
+	if o.signatureOrder {
+		sortJSON(view["signatures"])
+	}
+
+	if meta, _ := view["meta"].(map[string]interface{}); meta != nil {
+		if len(l.Meta.Data) > 0 {
+			meta["data"] = decodeData(l.Meta.Data)
+		}
+		if o.tagOrder {
+			sortJSON(meta["tags"])
+		}
+	}
+
+	return view
+}
+
+func toJSON(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var res interface{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil
+	}
+
+	return res
+}
+
+// decodeData decodes JSON data, or keeps the raw bytes if they aren't JSON.
+func decodeData(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return b
+	}
+
+	return data
+}
+
+// sortJSON sorts a JSON array by the JSON encoding of its items.
+func sortJSON(v interface{}) {
+	a, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+
+	keys := make(map[int]string, len(a))
+	for i, item := range a {
+		b, _ := json.Marshal(item)
+		keys[i] = string(b)
+	}
+
+	sorted := make([]interface{}, len(a))
+	indexes := make([]int, len(a))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return keys[indexes[i]] < keys[indexes[j]] })
+	for i, idx := range indexes {
+		sorted[i] = a[idx]
+	}
+
+	copy(a, sorted)
+}
+
+// diff appends the differences between two JSON values.
+func diff(path string, v1, v2 interface{}, diffs []string) []string {
+	switch a := v1.(type) {
+	case map[string]interface{}:
+		b, ok := v2.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(a)+len(b))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			diffs = diff(path+"."+k, a[k], b[k], diffs)
+		}
+
+		return diffs
+	case []interface{}:
+		b, ok := v2.([]interface{})
+		if !ok {
+			break
+		}
+
+		if len(a) != len(b) {
+			diffs = append(diffs, fmt.Sprintf("%s: length %d != %d", path, len(a), len(b)))
+		}
+
+		for i := 0; i < len(a) && i < len(b); i++ {
+			diffs = diff(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], diffs)
+		}
+
+		return diffs
+	}
+
+	if !reflect.DeepEqual(v1, v2) {
+		diffs = append(diffs, fmt.Sprintf("%s: %s != %s", path, format(v1), format(v2)))
+	}
+
+	return diffs
+}
+
+func format(v interface{}) string {
+	if v == nil {
+		return "<missing>"
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,152 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscripttest_test
+
+import (
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSegments(t *testing.T) {
+	newSegment := func(t *testing.T) *chainscript.Segment {
+		s := chainscripttest.NewLinkBuilder(t).
+			WithData(t, map[string]interface{}{"name": "batman", "age": 42}).
+			WithStep("init").
+			WithTags("a", "b").
+			Segmentify(t)
+
+		require.NoError(t, s.AddEvidence(&chainscript.Evidence{Version: "1.0.0", Backend: "btc", Provider: "main", Proof: []byte{1}}))
+		require.NoError(t, s.AddEvidence(&chainscript.Evidence{Version: "1.0.0", Backend: "eth", Provider: "main", Proof: []byte{2}}))
+		return s
+	}
+
+	t.Run("equal", func(t *testing.T) {
+		s := newSegment(t)
+		assert.Empty(t, chainscripttest.DiffSegments(s, s.Clone()))
+		chainscripttest.SegmentsEqual(t, s, s.Clone())
+	})
+
+	t.Run("decoded data", func(t *testing.T) {
+		s1, s2 := newSegment(t), newSegment(t)
+		require.NoError(t, s2.Link.SetData(map[string]interface{}{"name": "robin", "age": 42}))
+		s2.Link.Meta.Step = "done"
+
+		assert.Equal(t, []string{
+			`link.data.name: "batman" != "robin"`,
+			`link.meta.step: "init" != "done"`,
+		}, chainscripttest.DiffLinks(s1.Link, s2.Link))
+	})
+
+	t.Run("tag order", func(t *testing.T) {
+		s1, s2 := newSegment(t), newSegment(t)
+		s2.Link.Meta.Tags = []string{"b", "a"}
+		require.NoError(t, s2.SetLinkHash())
+
+		assert.Contains(t, chainscripttest.DiffSegments(s1, s2), `segment.link.meta.tags[0]: "a" != "b"`)
+
+		// Only the link hash is different when tags are sorted.
+		diffs := chainscripttest.DiffSegments(s1, s2, chainscripttest.IgnoreTagOrder())
+		require.Len(t, diffs, 1)
+		assert.Contains(t, diffs[0], "segment.meta.linkHash")
+
+		s2.Meta.LinkHash = s1.Meta.LinkHash
+		chainscripttest.SegmentsEqual(t, s1, s2, chainscripttest.IgnoreTagOrder())
+	})
+
+	t.Run("evidence order", func(t *testing.T) {
+		s1, s2 := newSegment(t), newSegment(t)
+		s2.Meta.Evidences[0], s2.Meta.Evidences[1] = s2.Meta.Evidences[1], s2.Meta.Evidences[0]
+
+		assert.Contains(t, chainscripttest.DiffSegments(s1, s2), `segment.meta.evidences[0].backend: "btc" != "eth"`)
+		chainscripttest.SegmentsEqual(t, s1, s2, chainscripttest.IgnoreEvidenceOrder())
+	})
+
+	t.Run("signature order", func(t *testing.T) {
+		l1 := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").WithSignature(t, "[version]").Build()
+		l2, _ := l1.Clone()
+		l2.Signatures[0], l2.Signatures[1] = l2.Signatures[1], l2.Signatures[0]
+
+		assert.NotEmpty(t, chainscripttest.DiffLinks(l1, l2))
+		chainscripttest.LinksEqual(t, l1, l2, chainscripttest.IgnoreSignatureOrder())
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		s1, s2 := newSegment(t), newSegment(t)
+		s2.Meta.Evidences = s2.Meta.Evidences[:1]
+		s2.Link.Meta.Tags = nil
+
+		assert.Equal(t, []string{
+			`segment.link.meta.tags: ["a","b"] != <missing>`,
+			"segment.meta.evidences: length 2 != 1",
+		}, chainscripttest.DiffSegments(s1, s2))
+	})
+}
+
+func TestSegmentMatches(t *testing.T) {
+	key := chainscripttest.RandomPrivateKey(t)
+	parent := chainscripttest.RandomLink(t)
+	ref := chainscripttest.RandomLink(t)
+
+	s := chainscripttest.NewLinkBuilder(t).
+		WithProcess("p").
+		WithMapID("m").
+		WithAction("approve").
+		WithStep("review").
+		WithTags("approved", "urgent").
+		WithParent(t, parent).
+		WithRef(t, ref).
+		WithData(t, map[string]interface{}{"amount": 42}).
+		WithMetadata(t, "notes").
+		WithSignatureFromKey(t, key, "").
+		Segmentify(t)
+	require.NoError(t, s.AddEvidence(&chainscript.Evidence{Version: "1.0.0", Backend: "btc", Provider: "main", Proof: []byte{1}}))
+
+	parentHash, _ := parent.Hash()
+	refHash, _ := ref.Hash()
+
+	chainscripttest.SegmentMatches(t, s,
+		chainscripttest.HasProcess("p"),
+		chainscripttest.HasMapID("m"),
+		chainscripttest.HasAction("approve"),
+		chainscripttest.HasStep("review"),
+		chainscripttest.HasTag("urgent"),
+		chainscripttest.HasParent(parentHash),
+		chainscripttest.HasRef(refHash),
+		chainscripttest.HasData(map[string]interface{}{"amount": 42}),
+		chainscripttest.HasMetadata("notes"),
+		chainscripttest.HasEvidence("btc", "main"),
+		chainscripttest.HasSignatures(1),
+		chainscripttest.HasSignatureFrom(s.Link.Signatures[0].PublicKey),
+	)
+
+	testCases := []struct {
+		matcher  chainscripttest.SegmentMatcher
+		mismatch string
+	}{
+		{chainscripttest.HasStep("init"), `step: "init" != "review"`},
+		{chainscripttest.HasTag("draft"), `tags: "draft" not in ["approved" "urgent"]`},
+		{chainscripttest.HasData(map[string]interface{}{"amount": 41}), "data.amount: 41 != 42"},
+		{chainscripttest.HasEvidence("eth", "main"), "evidences: no evidence from eth/main"},
+		{chainscripttest.HasSignatures(2), "signatures: 2 != 1"},
+	}
+
+	for _, tt := range testCases {
+		assert.Equal(t, tt.mismatch, tt.matcher(s))
+	}
+}
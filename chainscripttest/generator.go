@@ -0,0 +1,191 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscripttest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+)
+
+// maxDataDepth limits the nesting of generated data.
+const maxDataDepth = 4
+
+// Generator produces valid ChainScript values for property-based tests and
+// fuzz targets. The same seed always produces the same values, so a fuzz
+// target can take an int64 argument and build its inputs from it:
+//
+//	f.Fuzz(func(t *testing.T, seed int64) {
+//		s := chainscripttest.NewGenerator(seed).Segment()
+//		...
+//	})
+type Generator struct {
+	r *rand.Rand
+}
+
+// NewGenerator creates a generator from a seed.
+// Generators are safe for concurrent use, but values are only reproducible
+// when they are generated in the same order.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{r: rand.New(newLockedSource(seed))}
+}
+
+// ForAll checks a property with n generators, in subtests named after
+// their seed.
+func ForAll(t *testing.T, n int, property func(*testing.T, *Generator)) {
+	seeds := rand.New(rand.NewSource(source.r.Int63()))
+	for i := 0; i < n; i++ {
+		seed := seeds.Int63()
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			property(t, NewGenerator(seed))
+		})
+	}
+}
+
+// String returns a random UTF-8 string of at most n runes.
+func (g *Generator) String(n int) string {
+	runes := make([]rune, g.r.Intn(n+1))
+	for i := range runes {
+		switch g.r.Intn(4) {
+		case 0:
+			// Control characters and non-ASCII runes must be escaped.
+			runes[i] = rune(g.r.Intn(0x80))
+		case 1:
+			runes[i] = rune(0x80 + g.r.Intn(0xD800-0x80))
+		case 2:
+			runes[i] = rune(0x10000 + g.r.Intn(0x10FFFF-0x10000))
+		default:
+			runes[i] = letters[g.r.Intn(len(letters))]
+		}
+	}
+
+	return string(runes)
+}
+
+// Bytes returns random bytes of at most n bytes.
+func (g *Generator) Bytes(n int) []byte {
+	b := make([]byte, g.r.Intn(n+1))
+	readBytes(g.r, b)
+	return b
+}
+
+// Hash returns a random link hash.
+func (g *Generator) Hash() chainscript.LinkHash {
+	b := make([]byte, 32)
+	readBytes(g.r, b)
+	return b
+}
+
+// Data returns a random JSON value with the types produced by
+// StructurizeData when decoding into an interface{}: objects, arrays,
+// strings, float64 numbers, booleans and nil.
+func (g *Generator) Data() interface{} {
+	return g.data(0)
+}
+
+func (g *Generator) data(depth int) interface{} {
+	kinds := 7
+	if depth >= maxDataDepth {
+		kinds = 5
+	}
+
+	switch g.r.Intn(kinds) {
+	case 0:
+		return nil
+	case 1:
+		return g.r.Intn(2) == 0
+	case 2:
+		// Integers are exactly represented by float64 up to 2^53.
+		return float64(g.r.Int63n(1<<53) - 1<<52)
+	case 3:
+		return g.r.NormFloat64() * math.Pow10(g.r.Intn(20)-10)
+	case 4:
+		return g.String(32)
+	case 5:
+		a := make([]interface{}, g.r.Intn(5))
+		for i := range a {
+			a[i] = g.data(depth + 1)
+		}
+		return a
+	default:
+		m := make(map[string]interface{})
+		for i := g.r.Intn(5); i > 0; i-- {
+			m[g.String(12)] = g.data(depth + 1)
+		}
+		return m
+	}
+}
+
+// Evidence returns a random evidence.
+func (g *Generator) Evidence() *chainscript.Evidence {
+	return &chainscript.Evidence{
+		Version:  "1.0.0",
+		Backend:  randomString(g.r, 1+g.r.Intn(8)),
+		Provider: randomString(g.r, 1+g.r.Intn(12)),
+		Proof:    append([]byte{42}, g.Bytes(64)...),
+	}
+}
+
+// Link returns a random valid link (without signatures).
+func (g *Generator) Link() *chainscript.Link {
+	b := chainscript.NewLinkBuilder(randomString(g.r, 1+g.r.Intn(12)), randomString(g.r, 1+g.r.Intn(24))).
+		WithAction(g.String(12)).
+		WithData(g.Data()).
+		WithDegree(g.r.Intn(5) - 1).
+		WithMetadata(g.Data()).
+		WithPriority(g.r.Float64() * 100).
+		WithProcessState(g.String(12)).
+		WithStep(g.String(12))
+
+	for i := g.r.Intn(4); i > 0; i-- {
+		b = b.WithTags(g.String(8))
+	}
+
+	if g.r.Intn(2) == 0 {
+		b = b.WithParent(g.Hash())
+	}
+
+	for i := g.r.Intn(3); i > 0; i-- {
+		b = b.WithRefs(&chainscript.LinkReference{
+			LinkHash: g.Hash(),
+			Process:  randomString(g.r, 1+g.r.Intn(12)),
+		})
+	}
+
+	l, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return l
+}
+
+// Segment returns a segment containing a random link and evidences.
+func (g *Generator) Segment() *chainscript.Segment {
+	s, err := g.Link().Segmentify()
+	if err != nil {
+		panic(err)
+	}
+
+	for i := g.r.Intn(3); i > 0; i-- {
+		// Duplicate evidences are ignored.
+		_ = s.AddEvidence(g.Evidence())
+	}
+
+	return s
+}
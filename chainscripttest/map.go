@@ -0,0 +1,271 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscripttest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stretchr/testify/require"
+)
+
+// MapOptions configure RandomMap.
+// The zero value generates a small tree without references, signatures or
+// evidences.
+type MapOptions struct {
+	// Seed of the random generator.
+	// Zero picks a seed from the package source (see Seed), and Map.Seed
+	// can be used to reproduce a failure.
+	Seed int64
+
+	// Process and MapID of the links. Random if empty.
+	Process string
+	MapID   string
+
+	// Depth is the number of links between the root and the deepest leaves
+	// (included). Defaults to 3.
+	Depth int
+	// MaxChildren is the maximum number of children of a link. Defaults to 2.
+	// The out degree of each link allows its children.
+	MaxChildren int
+
+	// MaxRefs is the maximum number of references of a link. References
+	// point to RefTargets or to links previously generated in the map, which
+	// turns the tree into a DAG.
+	MaxRefs int
+	// RefTargets are segments (usually of other maps) that links can
+	// reference.
+	RefTargets []*chainscript.Segment
+
+	// MaxSignatures is the maximum number of signatures of a link.
+	// Each signature uses a different key from Keys.
+	MaxSignatures int
+	// Keys is the pool of private keys used to sign links.
+	// If empty, MaxSignatures keys are derived from the seed.
+	Keys [][]byte
+
+	// MaxEvidences is the maximum number of evidences of a segment.
+	MaxEvidences int
+}
+
+// Map is a consistent set of segments sharing a process and map ID.
+type Map struct {
+	Seed    int64
+	Process string
+	MapID   string
+	Keys    [][]byte
+
+	// Segments are sorted so that parents and references to other segments
+	// of the map come first. The first segment is the root.
+	Segments []*chainscript.Segment
+}
+
+// Root returns the first segment of the map.
+func (m *Map) Root() *chainscript.Segment {
+	return m.Segments[0]
+}
+
+// Leaves returns the segments that don't have children.
+func (m *Map) Leaves() []*chainscript.Segment {
+	parents := make(map[string]struct{})
+	for _, s := range m.Segments {
+		if prev := s.Link.PrevLinkHash(); len(prev) > 0 {
+			parents[prev.String()] = struct{}{}
+		}
+	}
+
+	var leaves []*chainscript.Segment
+	for _, s := range m.Segments {
+		if _, ok := parents[s.LinkHash().String()]; !ok {
+			leaves = append(leaves, s)
+		}
+	}
+
+	return leaves
+}
+
+// Resolver returns a resolver for the segments of the map.
+func (m *Map) Resolver() *chainscript.SegmentsResolver {
+	return chainscript.NewSegmentsResolver(m.Segments...)
+}
+
+// mapNode is a generated segment and its planned number of children.
+type mapNode struct {
+	segment  *chainscript.Segment
+	children int
+}
+
+// RandomMap generates a tree of valid segments (a DAG when links have
+// references) for a single process and map.
+func RandomMap(t testing.TB, opts *MapOptions) *Map {
+	o := MapOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Seed == 0 {
+		o.Seed = source.r.Int63()
+	}
+	if o.Depth <= 0 {
+		o.Depth = 3
+	}
+	if o.MaxChildren <= 0 {
+		o.MaxChildren = 2
+	}
+
+	r := rand.New(rand.NewSource(o.Seed))
+	if len(o.Process) == 0 {
+		o.Process = randomString(r, 12)
+	}
+	if len(o.MapID) == 0 {
+		o.MapID = randomString(r, 24)
+	}
+
+	keys := o.Keys
+	if len(keys) == 0 {
+		for i := 0; i < o.MaxSignatures; i++ {
+			key, err := privateKey(r)
+			require.NoError(t, err)
+			keys = append(keys, key)
+		}
+	}
+
+	m := &Map{Seed: o.Seed, Process: o.Process, MapID: o.MapID, Keys: keys}
+	g := &mapGenerator{t: t, r: r, opts: &o, keys: keys, m: m}
+
+	level := []*mapNode{g.node(nil, 0, true)}
+	for depth := 1; depth < o.Depth; depth++ {
+		var next []*mapNode
+		for _, parent := range level {
+			for i := 0; i < parent.children; i++ {
+				next = append(next, g.node(parent.segment, depth, len(next) == 0))
+			}
+		}
+
+		level = next
+	}
+
+	return m
+}
+
+// mapGenerator holds the state of RandomMap.
+type mapGenerator struct {
+	t    testing.TB
+	r    *rand.Rand
+	opts *MapOptions
+	keys [][]byte
+	m    *Map
+}
+
+// node generates a segment at the given depth. The first node of a level
+// always has a child so that the map reaches the requested depth.
+func (g *mapGenerator) node(parent *chainscript.Segment, depth int, first bool) *mapNode {
+	children := 0
+	if depth < g.opts.Depth-1 {
+		children = g.r.Intn(g.opts.MaxChildren + 1)
+		if first && children == 0 {
+			children = 1
+		}
+	}
+
+	outDegree := children
+	switch g.r.Intn(3) {
+	case 0:
+		outDegree = -1
+	case 1:
+		outDegree = children + 1
+	}
+
+	b := chainscript.NewLinkBuilder(g.opts.Process, g.opts.MapID).
+		WithAction(randomString(g.r, 8)).
+		WithData(map[string]interface{}{
+			"depth": depth,
+			"value": randomString(g.r, 16),
+		}).
+		WithDegree(outDegree).
+		WithPriority(g.r.Float64()).
+		WithProcessState(randomString(g.r, 8)).
+		WithStep(randomString(g.r, 8)).
+		WithTags(randomString(g.r, 6), randomString(g.r, 6)).
+		WithRefs(g.refs(parent)...)
+
+	if parent != nil {
+		b = b.WithParent(parent.LinkHash())
+	}
+
+	l, err := b.Build()
+	require.NoError(g.t, err)
+
+	n := g.r.Intn(g.opts.MaxSignatures + 1)
+	if n > len(g.keys) {
+		n = len(g.keys)
+	}
+	for _, i := range g.r.Perm(len(g.keys))[:n] {
+		require.NoError(g.t, l.Sign(g.keys[i], ""))
+	}
+
+	s, err := l.Segmentify()
+	require.NoError(g.t, err)
+
+	for i := g.r.Intn(g.opts.MaxEvidences + 1); i > 0; i-- {
+		proof := make([]byte, 32)
+		g.r.Read(proof)
+
+		e, err := chainscript.NewEvidence("1.0.0", randomString(g.r, 6), fmt.Sprintf("provider-%d", i), proof)
+		require.NoError(g.t, err)
+		require.NoError(g.t, s.AddEvidence(e))
+	}
+
+	g.m.Segments = append(g.m.Segments, s)
+	return &mapNode{segment: s, children: children}
+}
+
+// refs picks references among the reference targets and the segments
+// already generated, except the parent.
+func (g *mapGenerator) refs(parent *chainscript.Segment) []*chainscript.LinkReference {
+	var candidates []*chainscript.Segment
+	candidates = append(candidates, g.opts.RefTargets...)
+	for _, s := range g.m.Segments {
+		if s != parent {
+			candidates = append(candidates, s)
+		}
+	}
+
+	n := g.r.Intn(g.opts.MaxRefs + 1)
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+
+	var refs []*chainscript.LinkReference
+	for _, i := range g.r.Perm(len(candidates))[:n] {
+		refs = append(refs, &chainscript.LinkReference{
+			LinkHash: candidates[i].LinkHash(),
+			Process:  candidates[i].Link.Meta.Process.Name,
+		})
+	}
+
+	return refs
+}
+
+// randomString generates a random string with the given generator.
+func randomString(r *rand.Rand, n int) string {
+	b := make([]rune, n)
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,178 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscripttest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stretchr/testify/require"
+)
+
+// SegmentMatcher checks a property of a segment.
+// It returns a description of the mismatch, or an empty string.
+type SegmentMatcher func(*chainscript.Segment) string
+
+// SegmentMatches checks that the segment satisfies every matcher, and
+// reports all the mismatches:
+//
+//	chainscripttest.SegmentMatches(t, s, HasTag("approved"), HasStep("review"))
+func SegmentMatches(t testing.TB, s *chainscript.Segment, matchers ...SegmentMatcher) {
+	require.NotNil(t, s, "segment")
+	require.NotNil(t, s.Link, "segment link")
+	require.NotNil(t, s.Link.Meta, "segment link meta")
+
+	var mismatches []string
+	for _, m := range matchers {
+		if mismatch := m(s); len(mismatch) > 0 {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		require.FailNow(t, "segment doesn't match", strings.Join(mismatches, "\n"))
+	}
+}
+
+// HasAction matches the link's action.
+func HasAction(action string) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		return mismatch("action", action, s.Link.Meta.Action)
+	}
+}
+
+// HasStep matches the link's step.
+func HasStep(step string) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		return mismatch("step", step, s.Link.Meta.Step)
+	}
+}
+
+// HasProcess matches the link's process name.
+func HasProcess(process string) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		return mismatch("process", process, s.Link.Meta.GetProcess().GetName())
+	}
+}
+
+// HasMapID matches the link's map ID.
+func HasMapID(mapID string) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		return mismatch("map id", mapID, s.Link.Meta.MapId)
+	}
+}
+
+// HasTag matches links containing the tag.
+func HasTag(tag string) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		if _, ok := s.Link.TagMap()[tag]; !ok {
+			return fmt.Sprintf("tags: %q not in %q", tag, s.Link.Meta.Tags)
+		}
+
+		return ""
+	}
+}
+
+// HasParent matches the link's parent hash.
+func HasParent(linkHash chainscript.LinkHash) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		if !bytes.Equal(linkHash, s.Link.PrevLinkHash()) {
+			return fmt.Sprintf("parent: %s != %s", linkHash, s.Link.PrevLinkHash())
+		}
+
+		return ""
+	}
+}
+
+// HasRef matches links referencing the given link hash.
+func HasRef(linkHash chainscript.LinkHash) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		for _, ref := range s.Link.Meta.Refs {
+			if bytes.Equal(linkHash, ref.LinkHash) {
+				return ""
+			}
+		}
+
+		return fmt.Sprintf("refs: %s not referenced", linkHash)
+	}
+}
+
+// HasData matches the link's data, compared after a JSON round-trip of the
+// expected value.
+func HasData(data interface{}) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		return dataMismatch("data", data, s.Link.Data)
+	}
+}
+
+// HasMetadata matches the link's metadata, compared after a JSON round-trip
+// of the expected value.
+func HasMetadata(metadata interface{}) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		return dataMismatch("metadata", metadata, s.Link.Meta.Data)
+	}
+}
+
+// HasEvidence matches segments with an evidence from the backend and
+// provider.
+func HasEvidence(backend, provider string) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		if s.GetEvidence(backend, provider) == nil {
+			return fmt.Sprintf("evidences: no evidence from %s/%s", backend, provider)
+		}
+
+		return ""
+	}
+}
+
+// HasSignatureFrom matches links signed with the given public key.
+func HasSignatureFrom(publicKey []byte) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		for _, sig := range s.Link.Signatures {
+			if bytes.Equal(publicKey, sig.PublicKey) {
+				return ""
+			}
+		}
+
+		return "signatures: no signature from the public key"
+	}
+}
+
+// HasSignatures matches the number of link signatures.
+func HasSignatures(n int) SegmentMatcher {
+	return func(s *chainscript.Segment) string {
+		if len(s.Link.Signatures) != n {
+			return fmt.Sprintf("signatures: %d != %d", n, len(s.Link.Signatures))
+		}
+
+		return ""
+	}
+}
+
+func mismatch(field, expected, actual string) string {
+	if expected != actual {
+		return fmt.Sprintf("%s: %q != %q", field, expected, actual)
+	}
+
+	return ""
+}
+
+func dataMismatch(field string, expected interface{}, actual []byte) string {
+	diffs := diff(field, toJSON(expected), decodeData(actual), nil)
+	return strings.Join(diffs, "\n")
+}
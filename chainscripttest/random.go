@@ -16,19 +16,64 @@ package chainscripttest
 
 import (
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stratumn/go-chainscript"
 	"github.com/stratumn/go-crypto/keys"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
 )
 
 var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
+// lockedSource is a random source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed)}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// readBytes fills b with random bytes.
+// Unlike rand.Rand.Read, it is safe for concurrent use with a lockedSource.
+func readBytes(r *rand.Rand, b []byte) {
+	for i := range b {
+		b[i] = byte(r.Int63())
+	}
+}
+
+// source is used by the package-level helpers instead of the global
+// math/rand source, so that fixtures can be reproduced (see Seed).
+var source = NewGenerator(time.Now().UnixNano())
+
+// Seed seeds the random source of the package-level helpers (RandomBytes,
+// RandomLink, LinkBuilder.WithRandomData...).
+// Call it before running tests (for example in TestMain) to reproduce
+// generated fixtures, including private keys.
+func Seed(seed int64) {
+	source.r.Seed(seed)
+}
+
 // RandomBytes returns a random byte array of the specified length.
 func RandomBytes(n int) []byte {
 	b := make([]byte, n)
-	rand.Read(b)
+	readBytes(source.r, b)
 	return b
 }
 
@@ -39,36 +84,45 @@ func RandomHash() chainscript.LinkHash {
 
 // RandomString generates a random string.
 func RandomString(n int) string {
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
-	}
-	return string(b)
+	return randomString(source.r, n)
 }
 
-// RandomPrivateKey generates a private key that can be used to sign links.
-func RandomPrivateKey(t *testing.T) []byte {
-	_, privKey, err := keys.NewEd25519KeyPair()
-	require.NoError(t, err)
+// NewPrivateKey generates a private key that can be used to sign links.
+// The key is derived from the package random source (see Seed): it must
+// only be used in tests.
+func NewPrivateKey() ([]byte, error) {
+	return privateKey(source.r)
+}
+
+// privateKey derives an Ed25519 private key from a random source.
+func privateKey(r *rand.Rand) ([]byte, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	readBytes(r, seed)
+	privKey := ed25519.NewKeyFromSeed(seed)
 
-	keyBytes, err := keys.EncodeED25519SecretKey(privKey)
+	return keys.EncodeED25519SecretKey(&privKey)
+}
+
+// RandomPrivateKey generates a private key that can be used to sign links.
+func RandomPrivateKey(t testing.TB) []byte {
+	keyBytes, err := NewPrivateKey()
 	require.NoError(t, err)
 
 	return keyBytes
 }
 
 // RandomLink creates a link with random data.
-func RandomLink(t *testing.T) *chainscript.Link {
+func RandomLink(t testing.TB) *chainscript.Link {
 	return NewLinkBuilder(t).WithRandomData().Build()
 }
 
 // RandomSegment creates a segment with random data.
-func RandomSegment(t *testing.T) *chainscript.Segment {
+func RandomSegment(t testing.TB) *chainscript.Segment {
 	return NewLinkBuilder(t).WithRandomData().Segmentify(t)
 }
 
 // RandomEvidence creates a random evidence.
-func RandomEvidence(t *testing.T) *chainscript.Evidence {
+func RandomEvidence(t testing.TB) *chainscript.Evidence {
 	return &chainscript.Evidence{
 		Version:  "1.0.0",
 		Backend:  RandomString(6),
@@ -0,0 +1,35 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscripttest_test
+
+import (
+	"testing"
+
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeed(t *testing.T) {
+	chainscripttest.Seed(42)
+	key1 := chainscripttest.RandomPrivateKey(t)
+	link1 := chainscripttest.NewLinkBuilder(t).WithRandomData().WithSignature(t, "").Build()
+
+	chainscripttest.Seed(42)
+	key2 := chainscripttest.RandomPrivateKey(t)
+	link2 := chainscripttest.NewLinkBuilder(t).WithRandomData().WithSignature(t, "").Build()
+
+	assert.Equal(t, key1, key2)
+	chainscripttest.LinksEqual(t, link1, link2)
+}
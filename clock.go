@@ -0,0 +1,43 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"time"
+)
+
+// WithClock returns a context containing the clock used to check signature
+// keys and certificates when no trusted evidence time is available, and to
+// date evidences.
+func WithClock(ctx context.Context, clock func() time.Time) context.Context {
+	return context.WithValue(ctx, clockKey, clock)
+}
+
+// ClockFromContext returns the clock stored in the context, or nil if there
+// is none.
+func ClockFromContext(ctx context.Context) func() time.Time {
+	clock, _ := ctx.Value(clockKey).(func() time.Time)
+	return clock
+}
+
+// now returns the time given by the context's clock.
+func now(ctx context.Context) time.Time {
+	if clock := ClockFromContext(ctx); clock != nil {
+		return clock()
+	}
+
+	return time.Now()
+}
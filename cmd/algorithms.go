@@ -0,0 +1,237 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-crypto/keys"
+)
+
+// NewKeySignatureTest creates a test case with a single signature made with
+// a key of the given algorithm ("Ed25519" or "RSA").
+func NewKeySignatureTest(algorithm string) TestCase {
+	return &SegmentTest{
+		description: "A segment with a single " + chainscript.SignatureVersion1_0_0 + " signature of the whole link made with an " + algorithm + " key.",
+		generate: func() *chainscript.Segment {
+			link, err := chainscript.NewLinkBuilder("test_process", "test_map").
+				WithData(algorithm).
+				Build()
+			if err != nil {
+				panic(err)
+			}
+
+			if err := link.Sign(newPrivateKey(algorithm), ""); err != nil {
+				panic(err)
+			}
+
+			return mustSegmentify(link, nil)
+		},
+		check: func(s *chainscript.Segment) error {
+			if len(s.Link.Signatures) != 1 {
+				return errors.Errorf("invalid number of signatures: %d", len(s.Link.Signatures))
+			}
+
+			sig := s.Link.Signatures[0]
+			if sig.Version != chainscript.SignatureVersion1_0_0 {
+				return errors.Errorf("invalid signature version: %s", sig.Version)
+			}
+
+			if _, err := keys.ParsePublicKey(sig.PublicKey); err != nil {
+				return errors.Wrap(err, "invalid public key")
+			}
+
+			return sig.Validate(s.Link)
+		},
+	}
+}
+
+// newPrivateKey returns a PEM-encoded private key of the given algorithm.
+func newPrivateKey(algorithm string) []byte {
+	var key []byte
+	var err error
+
+	switch algorithm {
+	case "Ed25519":
+		_, sk, kerr := keys.NewEd25519KeyPair()
+		if kerr != nil {
+			panic(kerr)
+		}
+		key, err = keys.EncodeED25519SecretKey(sk)
+	case "RSA":
+		_, sk, kerr := keys.NewRSAKeyPair()
+		if kerr != nil {
+			panic(kerr)
+		}
+		key, err = keys.EncodeRSASecretKey(sk)
+	default:
+		panic("unknown algorithm " + algorithm)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// NewCertificateSignatureTest creates a test case with a single
+// certificate-based signature made with a key of the given algorithm ("RSA"
+// or "ECDSA").
+func NewCertificateSignatureTest(algorithm string) TestCase {
+	return &SegmentTest{
+		description: "A segment with a single " + chainscript.SignatureVersion1_1_0 + " signature of the whole link made with an " + algorithm + " X.509 certificate. The public key contains the PEM-encoded signer certificate followed by its self-signed issuer, which must be trusted to validate the segment.",
+		generate: func() *chainscript.Segment {
+			link, err := chainscript.NewLinkBuilder("test_process", "test_map").
+				WithData(algorithm).
+				Build()
+			if err != nil {
+				panic(err)
+			}
+
+			signer, chain := newCertificateChain(algorithm)
+			if err := link.SignWithCertificate(signer, chain, ""); err != nil {
+				panic(err)
+			}
+
+			return mustSegmentify(link, nil)
+		},
+		validationContext: func(s *chainscript.Segment) (context.Context, error) {
+			if len(s.Link.Signatures) != 1 {
+				return nil, errors.Errorf("invalid number of signatures: %d", len(s.Link.Signatures))
+			}
+
+			// The issuer is the last certificate of the chain.
+			var issuer *pem.Block
+			for rest := s.Link.Signatures[0].PublicKey; ; {
+				block, r := pem.Decode(rest)
+				if block == nil {
+					break
+				}
+				issuer, rest = block, r
+			}
+
+			if issuer == nil {
+				return nil, errors.New("missing certificate chain")
+			}
+
+			root, err := x509.ParseCertificate(issuer.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(root)
+
+			return chainscript.WithCertificateRoots(context.Background(), roots), nil
+		},
+		check: func(s *chainscript.Segment) error {
+			sig := s.Link.Signatures[0]
+			if sig.Version != chainscript.SignatureVersion1_1_0 {
+				return errors.Errorf("invalid signature version: %s", sig.Version)
+			}
+
+			signer, err := sig.Signer()
+			if err != nil {
+				return err
+			}
+
+			if signer.Subject.CommonName != "signer" {
+				return errors.Errorf("invalid signer: %s", signer.Subject)
+			}
+
+			return nil
+		},
+	}
+}
+
+// newCertificateChain creates a signer certificate issued by a self-signed
+// certificate, both using keys of the given algorithm.
+func newCertificateChain(algorithm string) (crypto.Signer, []*x509.Certificate) {
+	issuerKey, signerKey := newSigner(algorithm), newSigner(algorithm)
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.AddDate(100, 0, 0)
+
+	issuerTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "issuer"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	issuer := newCertificate(issuerTemplate, issuerTemplate, issuerKey.Public(), issuerKey)
+
+	signer := newCertificate(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, issuer, signerKey.Public(), issuerKey)
+
+	return signerKey, []*x509.Certificate{signer, issuer}
+}
+
+// newSigner creates a private key of the given algorithm.
+func newSigner(algorithm string) crypto.Signer {
+	var key crypto.Signer
+	var err error
+
+	switch algorithm {
+	case "RSA":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ECDSA":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		panic("unknown algorithm " + algorithm)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// newCertificate creates a certificate and panics on errors.
+func newCertificate(template, parent *x509.Certificate, pub interface{}, priv crypto.Signer) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	if err != nil {
+		panic(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	return cert
+}
@@ -0,0 +1,108 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+)
+
+// NewCountersignatureTest creates a test case with a countersignature.
+func NewCountersignatureTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with an Ed25519 signature of the whole link followed by an RSA countersignature of the link and the first signature (payload path `" + chainscript.CountersignPayloadPath(0) + "`).",
+		generate: func() *chainscript.Segment {
+			link, err := chainscript.NewLinkBuilder("test_process", "test_map").
+				WithAction("approve").
+				Build()
+			if err != nil {
+				panic(err)
+			}
+
+			if err := link.Sign(newPrivateKey("Ed25519"), ""); err != nil {
+				panic(err)
+			}
+
+			if err := link.Countersign(newPrivateKey("RSA"), link.Signatures[0]); err != nil {
+				panic(err)
+			}
+
+			return mustSegmentify(link, nil)
+		},
+		check: func(s *chainscript.Segment) error {
+			if len(s.Link.Signatures) != 2 {
+				return errors.Errorf("invalid number of signatures: %d", len(s.Link.Signatures))
+			}
+
+			countersig := s.Link.Signatures[1]
+			if countersig.PayloadPath != chainscript.CountersignPayloadPath(0) {
+				return errors.Errorf("invalid countersignature payload path: %s", countersig.PayloadPath)
+			}
+
+			// The countersignature must cover the first signature.
+			signed := s.Link.Signatures[0].Signature
+			s.Link.Signatures[0].Signature = nil
+			err := countersig.Validate(s.Link)
+			s.Link.Signatures[0].Signature = signed
+			if err == nil {
+				return errors.New("countersignature doesn't cover the first signature")
+			}
+
+			return countersig.Validate(s.Link)
+		},
+	}
+}
+
+// NewMissingCountersignTargetTest creates a test case with a
+// countersignature of a signature that doesn't exist.
+func NewMissingCountersignTargetTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with a valid Ed25519 countersignature of a signature that doesn't exist (payload path `" + chainscript.CountersignPayloadPath(1) + "`).",
+		errorCode:   chainscript.CodeMissingCountersignTarget,
+		generate: func() *chainscript.Segment {
+			return invalidSegment(func(l *chainscript.Link) {
+				if err := l.Sign(newPrivateKey("Ed25519"), chainscript.CountersignPayloadPath(1)); err != nil {
+					panic(err)
+				}
+			})
+		},
+	}
+}
+
+// NewCountersignTargetOrderTest creates a test case with a countersignature
+// preceding the signature it countersigns.
+func NewCountersignTargetOrderTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with a valid Ed25519 countersignature of the next signature (payload path `" + chainscript.CountersignPayloadPath(1) + "`), followed by a valid Ed25519 signature of the whole link. Countersigned signatures must precede their countersignatures.",
+		errorCode:   chainscript.CodeCountersignTargetOrder,
+		generate: func() *chainscript.Segment {
+			return invalidSegment(func(l *chainscript.Link) {
+				if err := l.Sign(newPrivateKey("Ed25519"), ""); err != nil {
+					panic(err)
+				}
+
+				// Sign the countersignature while the target is at index 1,
+				// then move it before the target.
+				target := l.Signatures[0]
+				l.Signatures = []*chainscript.Signature{target, target}
+				if err := l.Sign(newPrivateKey("Ed25519"), chainscript.CountersignPayloadPath(1)); err != nil {
+					panic(err)
+				}
+
+				l.Signatures = []*chainscript.Signature{l.Signatures[2], target}
+			})
+		},
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+)
+
+// unicodeText contains characters that are often mishandled: multi-byte
+// characters, surrogate pairs, combining marks, right-to-left text,
+// zero-width joiners and control characters.
+const unicodeText = "ʙᴀᴛᴍᴀɴ 日本語 Ελληνικά עברית العربية é 👩‍👩‍👧‍👦 🇫🇷 ​\u0000\t\n\"\\ \U0010FFFF"
+
+// largeDataSize is the size of the data of the large-data test case.
+const largeDataSize = 256 * 1024
+
+// NewUnicodeTest creates a test case with Unicode text in every string field.
+func NewUnicodeTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with Unicode text (multi-byte characters, emojis, combining marks, right-to-left scripts, control characters) in its data, metadata, process, map, action, step and tags.",
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("process "+unicodeText, "map "+unicodeText).
+				WithAction("action "+unicodeText).
+				WithData(map[string]string{unicodeText: unicodeText}).
+				WithMetadata(unicodeText).
+				WithProcessState("state "+unicodeText).
+				WithStep("step "+unicodeText).
+				WithTags(unicodeText, "🏷").
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			meta := s.Link.Meta
+			for field, value := range map[string]string{
+				"process": meta.Process.Name,
+				"map":     meta.MapId,
+				"action":  meta.Action,
+				"state":   meta.Process.State,
+				"step":    meta.Step,
+			} {
+				if value != field+" "+unicodeText {
+					return errors.Errorf("invalid %s: %q", field, value)
+				}
+			}
+
+			if len(meta.Tags) != 2 || meta.Tags[0] != unicodeText || meta.Tags[1] != "🏷" {
+				return errors.Errorf("invalid tags: %q", meta.Tags)
+			}
+
+			var data map[string]string
+			if err := s.Link.StructurizeData(&data); err != nil {
+				return err
+			}
+			if len(data) != 1 || data[unicodeText] != unicodeText {
+				return errors.Errorf("invalid data: %q", data)
+			}
+
+			var metadata string
+			if err := s.Link.StructurizeMetadata(&metadata); err != nil {
+				return err
+			}
+			if metadata != unicodeText {
+				return errors.Errorf("invalid metadata: %q", metadata)
+			}
+
+			return nil
+		},
+	}
+}
+
+// largeData returns a deterministic string of the given size.
+func largeData(size int) string {
+	var b strings.Builder
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, "%08x", i)
+	}
+
+	return b.String()[:size]
+}
+
+// NewLargeDataTest creates a test case with large data.
+func NewLargeDataTest() TestCase {
+	return &SegmentTest{
+		description: fmt.Sprintf("A segment with large data: a %d KiB string made of the hexadecimal encoding of consecutive integers (\"0000000000000001...\").", largeDataSize/1024),
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithData(largeData(largeDataSize)).
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			var data string
+			if err := s.Link.StructurizeData(&data); err != nil {
+				return err
+			}
+
+			if len(data) != largeDataSize {
+				return errors.Errorf("invalid data size: %d", len(data))
+			}
+
+			if data != largeData(largeDataSize) {
+				return errors.New("invalid data content")
+			}
+
+			return nil
+		},
+	}
+}
+
+// nestedMetadata returns metadata nested at the given depth, using the types
+// produced by JSON decoding.
+func nestedMetadata(depth int) interface{} {
+	if depth == 0 {
+		return map[string]interface{}{
+			"string": "leaf",
+			"number": 42.0,
+			"bool":   true,
+			"null":   nil,
+			"array":  []interface{}{},
+			"object": map[string]interface{}{},
+		}
+	}
+
+	return map[string]interface{}{
+		"depth":    float64(depth),
+		"children": []interface{}{nestedMetadata(depth - 1), []interface{}{"sibling", float64(depth)}},
+	}
+}
+
+// nestedMetadataDepth is the depth of the nested-metadata test case.
+const nestedMetadataDepth = 32
+
+// NewNestedMetadataTest creates a test case with deeply nested metadata.
+func NewNestedMetadataTest() TestCase {
+	return &SegmentTest{
+		description: fmt.Sprintf("A segment with metadata nested %d levels deep: each level is an object with a `depth` number and a `children` array containing the next level and a `[\"sibling\", depth]` array. The last level contains a value of each JSON type.", nestedMetadataDepth),
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithMetadata(nestedMetadata(nestedMetadataDepth)).
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			var metadata interface{}
+			if err := s.Link.StructurizeMetadata(&metadata); err != nil {
+				return err
+			}
+
+			if !reflect.DeepEqual(metadata, nestedMetadata(nestedMetadataDepth)) {
+				return errors.Errorf("invalid metadata: %v", metadata)
+			}
+
+			return nil
+		},
+	}
+}
+
+// floatValues are edge values for numbers in data and priorities.
+var floatValues = map[string]float64{
+	"max":         math.MaxFloat64,
+	"smallest":    math.SmallestNonzeroFloat64,
+	"negative":    -1.5,
+	"zero":        0,
+	"epsilon":     2.220446049250313e-16,
+	"maxSafeInt":  9007199254740991,
+	"negativeMax": -math.MaxFloat64,
+}
+
+// NewFloatValuesTest creates a test case with float edge values.
+func NewFloatValuesTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with the maximum float64 value as priority and float edge values in its data: `max` (1.7976931348623157e308), `smallest` (5e-324), `negative` (-1.5), `zero`, `epsilon` (2.220446049250313e-16), `maxSafeInt` (2^53 - 1) and `negativeMax`.",
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithData(floatValues).
+				WithPriority(math.MaxFloat64).
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			if s.Link.Meta.Priority != math.MaxFloat64 {
+				return errors.Errorf("invalid priority: %g", s.Link.Meta.Priority)
+			}
+
+			var data map[string]float64
+			if err := s.Link.StructurizeData(&data); err != nil {
+				return err
+			}
+
+			if !reflect.DeepEqual(data, floatValues) {
+				return errors.Errorf("invalid data: %v", data)
+			}
+
+			return nil
+		},
+	}
+}
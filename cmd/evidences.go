@@ -16,7 +16,6 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"encoding/base64"
 
 	"github.com/pkg/errors"
@@ -31,6 +30,16 @@ func NewEvidencesTest() TestCase {
 	return &EvidencesTest{}
 }
 
+// Description of the segment.
+func (t *EvidencesTest) Description() string {
+	return "A segment with two external evidences."
+}
+
+// ErrorCode returns an empty string: the segment is valid.
+func (t *EvidencesTest) ErrorCode() string {
+	return ""
+}
+
 // Generate encoded segment bytes.
 func (t *EvidencesTest) Generate() string {
 	link, err := chainscript.NewLinkBuilder("test_process", "test_map").
@@ -72,23 +81,8 @@ func (t *EvidencesTest) Generate() string {
 	return base64.StdEncoding.EncodeToString(b)
 }
 
-// Validate encoded segment bytes.
-func (t *EvidencesTest) Validate(encoded string) error {
-	b, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return err
-	}
-
-	segment, err := chainscript.UnmarshalSegment(b)
-	if err != nil {
-		return err
-	}
-
-	err = segment.Validate(context.Background())
-	if err != nil {
-		return err
-	}
-
+// Check the decoded segment.
+func (t *EvidencesTest) Check(segment *chainscript.Segment) error {
 	if len(segment.Meta.Evidences) != 2 {
 		return errors.Errorf("invalid evidences count: %d", len(segment.Meta.Evidences))
 	}
@@ -0,0 +1,243 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+)
+
+// NewMissingFieldsTest creates a test case where every optional field is
+// missing.
+func NewMissingFieldsTest() TestCase {
+	return &SegmentTest{
+		description: "A segment where every optional field is missing: only the link version, client ID, out-degree (-1), process name and map ID are set.",
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			l := s.Link
+			switch {
+			case len(l.Data) != 0:
+				return errors.Errorf("invalid data: %s", l.Data)
+			case len(l.Meta.Data) != 0:
+				return errors.Errorf("invalid metadata: %s", l.Meta.Data)
+			case len(l.Meta.PrevLinkHash) != 0:
+				return errors.Errorf("invalid parent: %v", l.Meta.PrevLinkHash)
+			case l.Meta.Priority != 0:
+				return errors.Errorf("invalid priority: %g", l.Meta.Priority)
+			case l.Meta.OutDegree != -1:
+				return errors.Errorf("invalid degree: %d", l.Meta.OutDegree)
+			case len(l.Meta.Action) != 0 || len(l.Meta.Step) != 0 || len(l.Meta.Process.State) != 0:
+				return errors.Errorf("invalid action, step or state: %s, %s, %s", l.Meta.Action, l.Meta.Step, l.Meta.Process.State)
+			case len(l.Meta.Tags) != 0 || len(l.Meta.Refs) != 0:
+				return errors.Errorf("invalid tags or refs: %v, %v", l.Meta.Tags, l.Meta.Refs)
+			case len(l.Signatures) != 0 || len(s.Meta.Evidences) != 0:
+				return errors.New("unexpected signatures or evidences")
+			}
+
+			return nil
+		},
+	}
+}
+
+// NewEmptyFieldsTest creates a test case where data and metadata are empty
+// but not missing.
+func NewEmptyFieldsTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with empty (but not missing) data and metadata: the data is an empty JSON object (`{}`) and the metadata an empty JSON string (`\"\"`). They must not be confused with missing values.",
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithData(map[string]interface{}{}).
+				WithMetadata("").
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			var data map[string]interface{}
+			if err := s.Link.StructurizeData(&data); err != nil {
+				return err
+			}
+			if data == nil || len(data) != 0 {
+				return errors.Errorf("invalid data: %s", s.Link.Data)
+			}
+
+			metadata := "not empty"
+			if err := s.Link.StructurizeMetadata(&metadata); err != nil {
+				return err
+			}
+			if metadata != "" {
+				return errors.Errorf("invalid metadata: %s", s.Link.Meta.Data)
+			}
+
+			return nil
+		},
+	}
+}
+
+// NewDegreeAndPriorityTest creates a test case with a negative out-degree
+// and a zero priority.
+func NewDegreeAndPriorityTest() TestCase {
+	return &SegmentTest{
+		description: fmt.Sprintf("A segment with the smallest negative out-degree (%d, any negative value means that the link can have any number of children) and an explicit zero priority.", math.MinInt32),
+		generate: func() *chainscript.Segment {
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithDegree(math.MinInt32).
+				WithPriority(0).
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			if s.Link.Meta.OutDegree != math.MinInt32 {
+				return errors.Errorf("invalid degree: %d", s.Link.Meta.OutDegree)
+			}
+
+			if s.Link.Meta.Priority != 0 {
+				return errors.Errorf("invalid priority: %g", s.Link.Meta.Priority)
+			}
+
+			return nil
+		},
+	}
+}
+
+// manyTagsCount is the number of tags of the many-tags test case.
+const manyTagsCount = 1000
+
+// NewManyTagsTest creates a test case with many tags.
+func NewManyTagsTest() TestCase {
+	return &SegmentTest{
+		description: fmt.Sprintf("A segment with %d tags (`tag-0000` to `tag-%04d`), whose order must be preserved.", manyTagsCount, manyTagsCount-1),
+		generate: func() *chainscript.Segment {
+			tags := make([]string, manyTagsCount)
+			for i := range tags {
+				tags[i] = fmt.Sprintf("tag-%04d", i)
+			}
+
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithTags(tags...).
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			tags := s.Link.Meta.Tags
+			if len(tags) != manyTagsCount {
+				return errors.Errorf("invalid tags count: %d", len(tags))
+			}
+
+			for i, tag := range tags {
+				if tag != fmt.Sprintf("tag-%04d", i) {
+					return errors.Errorf("invalid tag %d: %s", i, tag)
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// manyRefsCount is the number of references of the many-refs test case.
+const manyRefsCount = 1000
+
+// manyRefsRef returns the i-th reference of the many-refs test case.
+func manyRefsRef(i int) *chainscript.LinkReference {
+	lh := sha256.Sum256([]byte(fmt.Sprintf("%d", i)))
+	return &chainscript.LinkReference{
+		LinkHash: lh[:],
+		Process:  fmt.Sprintf("p%d", i%10),
+	}
+}
+
+// NewManyRefsTest creates a test case with many references.
+func NewManyRefsTest() TestCase {
+	return &SegmentTest{
+		description: fmt.Sprintf("A segment with %d references. The i-th reference has the SHA-256 hash of the decimal string of i as link hash and `p{i mod 10}` as process.", manyRefsCount),
+		generate: func() *chainscript.Segment {
+			refs := make([]*chainscript.LinkReference, manyRefsCount)
+			for i := range refs {
+				refs[i] = manyRefsRef(i)
+			}
+
+			return mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithRefs(refs...).
+				Build())
+		},
+		check: func(s *chainscript.Segment) error {
+			refs := s.Link.Meta.Refs
+			if len(refs) != manyRefsCount {
+				return errors.Errorf("invalid references count: %d", len(refs))
+			}
+
+			for i, ref := range refs {
+				expected := manyRefsRef(i)
+				if ref.Process != expected.Process || !bytes.Equal(ref.LinkHash, expected.LinkHash) {
+					return errors.Errorf("invalid reference %d: %s %x", i, ref.Process, ref.LinkHash)
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// unknownFields returns the encoding of protobuf fields that aren't defined
+// in ChainScript: a string (field 1000) and a varint (field 1001).
+func unknownFields() []byte {
+	b := proto.NewBuffer(nil)
+	if err := b.EncodeVarint(1000<<3 | proto.WireBytes); err != nil {
+		panic(err)
+	}
+	if err := b.EncodeStringBytes("unknown"); err != nil {
+		panic(err)
+	}
+	if err := b.EncodeVarint(1001<<3 | proto.WireVarint); err != nil {
+		panic(err)
+	}
+	if err := b.EncodeVarint(42); err != nil {
+		panic(err)
+	}
+
+	return b.Bytes()
+}
+
+// NewUnknownFieldsTest creates a test case with unknown protobuf fields.
+func NewUnknownFieldsTest() TestCase {
+	return &SegmentTest{
+		description: "A segment containing protobuf fields that aren't defined by ChainScript (a string with number 1000 and a varint with number 1001) in the segment and in the segment's meta. They must be ignored when decoding. The link itself doesn't contain unknown fields since they would change its hash.",
+		generate: func() *chainscript.Segment {
+			s := mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+				WithAction("init").
+				Build())
+			s.XXX_unrecognized = unknownFields()
+			s.Meta.XXX_unrecognized = unknownFields()
+
+			return s
+		},
+		check: func(s *chainscript.Segment) error {
+			if s.Link.Meta.Action != "init" {
+				return errors.Errorf("invalid action: %s", s.Link.Meta.Action)
+			}
+
+			if s.Link.Meta.Process.Name != "test_process" || s.Link.Meta.MapId != "test_map" {
+				return errors.Errorf("invalid process or map: %s, %s", s.Link.Meta.Process.Name, s.Link.Meta.MapId)
+			}
+
+			return nil
+		},
+	}
+}
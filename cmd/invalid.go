@@ -0,0 +1,137 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/stratumn/go-chainscript"
+)
+
+// invalidSegment returns a valid segment modified by the given function.
+// The link hash is updated after the modification.
+func invalidSegment(modify func(*chainscript.Link)) *chainscript.Segment {
+	segment := mustSegmentify(chainscript.NewLinkBuilder("test_process", "test_map").
+		WithAction("init").
+		Build())
+
+	modify(segment.Link)
+	if err := segment.SetLinkHash(); err != nil {
+		panic(err)
+	}
+
+	return segment
+}
+
+// NewInvalidLinkHashTest creates a test case with a tampered link hash.
+func NewInvalidLinkHashTest() TestCase {
+	return &SegmentTest{
+		description: "A segment whose link hash doesn't match its link: the link's action was changed after the link hash was computed.",
+		errorCode:   chainscript.CodeLinkHashMismatch,
+		generate: func() *chainscript.Segment {
+			segment := invalidSegment(func(*chainscript.Link) {})
+			segment.Link.Meta.Action = "tampered"
+
+			return segment
+		},
+	}
+}
+
+// NewInvalidSignatureTest creates a test case with a broken signature.
+func NewInvalidSignatureTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with an Ed25519 signature that doesn't match the link: the link's action was changed after it was signed.",
+		errorCode:   chainscript.CodeInvalidSignature,
+		generate: func() *chainscript.Segment {
+			return invalidSegment(func(l *chainscript.Link) {
+				if err := l.Sign(newPrivateKey("Ed25519"), ""); err != nil {
+					panic(err)
+				}
+
+				l.Meta.Action = "tampered"
+			})
+		},
+	}
+}
+
+// NewUnknownClientIDTest creates a test case with an unknown client ID.
+func NewUnknownClientIDTest() TestCase {
+	return &SegmentTest{
+		description: "A segment whose link was created by an unknown client (`github.com/stratumn/unknown-chainscript`).",
+		errorCode:   chainscript.CodeUnknownClientID,
+		generate: func() *chainscript.Segment {
+			return invalidSegment(func(l *chainscript.Link) {
+				l.Meta.ClientId = "github.com/stratumn/unknown-chainscript"
+			})
+		},
+	}
+}
+
+// NewUnknownLinkVersionTest creates a test case with an unknown link
+// version.
+func NewUnknownLinkVersionTest() TestCase {
+	return &SegmentTest{
+		description: "A segment whose link has an unknown version (`0.42.0`). Its link hash is the hash of the link with version `" + chainscript.LinkVersion + "`.",
+		errorCode:   chainscript.CodeUnknownLinkVersion,
+		generate: func() *chainscript.Segment {
+			segment := invalidSegment(func(*chainscript.Link) {})
+			segment.Link.Version = "0.42.0"
+
+			return segment
+		},
+	}
+}
+
+// NewMissingProcessTest creates a test case without a process name.
+func NewMissingProcessTest() TestCase {
+	return &SegmentTest{
+		description: "A segment whose link doesn't have a process name.",
+		errorCode:   chainscript.CodeMissingProcess,
+		generate: func() *chainscript.Segment {
+			return invalidSegment(func(l *chainscript.Link) {
+				l.Meta.Process.Name = ""
+			})
+		},
+	}
+}
+
+// NewMissingMapIDTest creates a test case without a map ID.
+func NewMissingMapIDTest() TestCase {
+	return &SegmentTest{
+		description: "A segment whose link doesn't have a map ID.",
+		errorCode:   chainscript.CodeMissingMapID,
+		generate: func() *chainscript.Segment {
+			return invalidSegment(func(l *chainscript.Link) {
+				l.Meta.MapId = ""
+			})
+		},
+	}
+}
+
+// NewDuplicateEvidencesTest creates a test case with two evidences from the
+// same backend and provider.
+func NewDuplicateEvidencesTest() TestCase {
+	return &SegmentTest{
+		description: "A segment with two evidences from the same backend (`bitcoin`) and provider (`testnet`).",
+		errorCode:   chainscript.CodeDuplicateEvidence,
+		generate: func() *chainscript.Segment {
+			segment := invalidSegment(func(*chainscript.Link) {})
+			segment.Meta.Evidences = []*chainscript.Evidence{
+				{Version: "0.1.0", Backend: "bitcoin", Provider: "testnet", Proof: []byte{42}},
+				{Version: "0.1.0", Backend: "bitcoin", Provider: "testnet", Proof: []byte{24}},
+			}
+
+			return segment
+		},
+	}
+}
@@ -20,13 +20,21 @@
 //  * this test suite should be updated to cover the new features
 //  * snapshot encoded bytes of the previous version should be added to the
 //  tests in https://github.com/stratumn/chainscript/samples.
+//
+// The docs action documents the test cases (see README.md).
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript/samples"
 )
 
 var (
@@ -36,9 +44,46 @@ var (
 		"segment-references": NewReferencesTest(),
 		"segment-evidences":  NewEvidencesTest(),
 		"segment-signatures": NewSignaturesTest(),
+
+		"unicode-data":         NewUnicodeTest(),
+		"large-data":           NewLargeDataTest(),
+		"nested-metadata":      NewNestedMetadataTest(),
+		"float-values":         NewFloatValuesTest(),
+		"missing-fields":       NewMissingFieldsTest(),
+		"empty-fields":         NewEmptyFieldsTest(),
+		"degree-and-priority":  NewDegreeAndPriorityTest(),
+		"many-tags":            NewManyTagsTest(),
+		"many-refs":            NewManyRefsTest(),
+		"unknown-fields":       NewUnknownFieldsTest(),
+		"signature-ed25519":    NewKeySignatureTest("Ed25519"),
+		"signature-rsa":        NewKeySignatureTest("RSA"),
+		"signature-x509-rsa":   NewCertificateSignatureTest("RSA"),
+		"signature-x509-ecdsa": NewCertificateSignatureTest("ECDSA"),
+		"countersignature":     NewCountersignatureTest(),
+
+		"invalid-link-hash":          NewInvalidLinkHashTest(),
+		"invalid-signature":          NewInvalidSignatureTest(),
+		"unknown-client-id":          NewUnknownClientIDTest(),
+		"unknown-link-version":       NewUnknownLinkVersionTest(),
+		"missing-process":            NewMissingProcessTest(),
+		"missing-map-id":             NewMissingMapIDTest(),
+		"duplicate-evidences":        NewDuplicateEvidencesTest(),
+		"missing-countersign-target": NewMissingCountersignTargetTest(),
+		"countersign-target-order":   NewCountersignTargetOrderTest(),
 	}
 )
 
+// TestCaseIDs returns the IDs of the test cases in alphabetical order.
+func TestCaseIDs() []string {
+	var ids []string
+	for id := range TestCases {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
 func main() {
 	if len(os.Args) < 3 {
 		panic("Some arguments are missing. Please provide the action requested and the file path.")
@@ -52,6 +97,8 @@ func main() {
 		generate(path)
 	case "validate":
 		validate(path)
+	case "docs":
+		writeDocs(path)
 	default:
 		panic(fmt.Sprintf("Unknown action %s", action))
 	}
@@ -59,11 +106,12 @@ func main() {
 
 // generate encoded test segments and save them at the specified path.
 func generate(path string) {
-	var results []TestData
-	for id, t := range TestCases {
-		results = append(results, TestData{
-			ID:   id,
-			Data: t.Generate(),
+	var results []*samples.Sample
+	for _, id := range TestCaseIDs() {
+		results = append(results, &samples.Sample{
+			ID:        id,
+			Data:      TestCases[id].Generate(),
+			ErrorCode: TestCases[id].ErrorCode(),
 		})
 	}
 
@@ -82,35 +130,31 @@ func generate(path string) {
 }
 
 // validate encoded test segments from the specified path.
+// Test cases missing from the file are skipped, since other implementations
+// may not support them yet.
 func validate(path string) {
 	fmt.Printf("Loading encoded segments from %s...\n", path)
-	b, err := ioutil.ReadFile(path)
+	testData, err := samples.Load(path)
 	if err != nil {
 		panic(err)
 	}
 
-	var testData []TestData
-	err = json.Unmarshal(b, &testData)
-	if err != nil {
-		panic(err)
+	expectations := make(map[string]samples.Expectation, len(TestCases))
+	for id, tt := range TestCases {
+		expectations[id] = tt
 	}
 
 	failed := false
 
-	for _, t := range testData {
-		tt, ok := TestCases[t.ID]
-		if !ok {
-			fmt.Printf("Unkown test case: %s\n", t.ID)
+	for _, r := range samples.Run(context.Background(), testData, expectations) {
+		switch {
+		case errors.Cause(r.Err) == samples.ErrMissingSample:
+			fmt.Printf("[%s] SKIPPED\n", r.ID)
+		case r.Err != nil:
+			fmt.Printf("[%s] FAILED: %s\n", r.ID, r.Err.Error())
 			failed = true
-			continue
-		}
-
-		err = tt.Validate(t.Data)
-		if err != nil {
-			fmt.Printf("[%s] FAILED: %s\n", t.ID, err.Error())
-			failed = true
-		} else {
-			fmt.Printf("[%s] SUCCESS\n", t.ID)
+		default:
+			fmt.Printf("[%s] SUCCESS\n", r.ID)
 		}
 	}
 
@@ -118,3 +162,50 @@ func validate(path string) {
 		os.Exit(1)
 	}
 }
+
+// Docs returns the markdown documentation of the test cases.
+func Docs() []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintln(&b, "# Compatibility Test Cases")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "<!-- Generated with `go run cmd/*.go docs cmd/README.md`. DO NOT EDIT. -->")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "Every ChainScript implementation generates the following segments with")
+	fmt.Fprintln(&b, "the `generate` action, and checks that it can decode and validate the")
+	fmt.Fprintln(&b, "segments generated by the other implementations with the `validate` action.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "## Valid segments")
+	fmt.Fprintln(&b)
+
+	for _, id := range TestCaseIDs() {
+		if tt := TestCases[id]; len(tt.ErrorCode()) == 0 {
+			fmt.Fprintf(&b, "**%s**: %s\n\n", id, tt.Description())
+		}
+	}
+
+	fmt.Fprintln(&b, "## Invalid segments")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "These segments must be rejected with a validation error that has the")
+	fmt.Fprintln(&b, "expected code (the `errorCode` field of the test data).")
+	fmt.Fprintln(&b)
+
+	for _, id := range TestCaseIDs() {
+		if tt := TestCases[id]; len(tt.ErrorCode()) > 0 {
+			fmt.Fprintf(&b, "**%s** (`%s`): %s\n\n", id, tt.ErrorCode(), tt.Description())
+		}
+	}
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// writeDocs saves the documentation of the test cases at the specified path.
+func writeDocs(path string) {
+	fmt.Printf("Saving test cases documentation to %s...\n", path)
+	err := ioutil.WriteFile(path, Docs(), os.ModePerm)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("Saved.")
+}
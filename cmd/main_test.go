@@ -0,0 +1,42 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stratumn/go-chainscript/samples"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestCases(t *testing.T) {
+	for _, id := range TestCaseIDs() {
+		tt := TestCases[id]
+		t.Run(id, func(t *testing.T) {
+			assert.NotEmpty(t, tt.Description())
+			s := &samples.Sample{ID: id, Data: tt.Generate(), ErrorCode: tt.ErrorCode()}
+			assert.NoError(t, samples.Validate(context.Background(), s, tt))
+		})
+	}
+}
+
+func TestDocs(t *testing.T) {
+	readme, err := ioutil.ReadFile("README.md")
+	require.NoError(t, err)
+	assert.Equal(t, string(Docs()), string(readme), "cmd/README.md is outdated: run `go run cmd/*.go docs cmd/README.md`")
+}
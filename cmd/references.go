@@ -16,7 +16,6 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"encoding/base64"
 
 	"github.com/pkg/errors"
@@ -31,6 +30,16 @@ func NewReferencesTest() TestCase {
 	return &ReferencesTest{}
 }
 
+// Description of the segment.
+func (t *ReferencesTest) Description() string {
+	return "A segment with two references to other segments."
+}
+
+// ErrorCode returns an empty string: the segment is valid.
+func (t *ReferencesTest) ErrorCode() string {
+	return ""
+}
+
 // Generate encoded segment bytes.
 func (t *ReferencesTest) Generate() string {
 	link, err := chainscript.NewLinkBuilder("test_process", "test_map").
@@ -56,23 +65,8 @@ func (t *ReferencesTest) Generate() string {
 	return base64.StdEncoding.EncodeToString(b)
 }
 
-// Validate encoded segment bytes.
-func (t *ReferencesTest) Validate(encoded string) error {
-	b, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return err
-	}
-
-	segment, err := chainscript.UnmarshalSegment(b)
-	if err != nil {
-		return err
-	}
-
-	err = segment.Validate(context.Background())
-	if err != nil {
-		return err
-	}
-
+// Check the decoded segment.
+func (t *ReferencesTest) Check(segment *chainscript.Segment) error {
 	refs := segment.Link.Meta.Refs
 
 	if len(refs) != 2 {
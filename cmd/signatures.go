@@ -15,7 +15,6 @@
 package main
 
 import (
-	"context"
 	"encoding/base64"
 
 	"github.com/pkg/errors"
@@ -31,6 +30,16 @@ func NewSignaturesTest() TestCase {
 	return &SignaturesTest{}
 }
 
+// Description of the segment.
+func (t *SignaturesTest) Description() string {
+	return "A segment with two link signatures (RSA and Ed25519)."
+}
+
+// ErrorCode returns an empty string: the segment is valid.
+func (t *SignaturesTest) ErrorCode() string {
+	return ""
+}
+
 // Generate encoded segment bytes.
 func (t *SignaturesTest) Generate() string {
 	link, err := chainscript.NewLinkBuilder("test_process", "test_map").
@@ -83,28 +92,13 @@ func (t *SignaturesTest) Generate() string {
 	return base64.StdEncoding.EncodeToString(b)
 }
 
-// Validate encoded segment bytes.
-func (t *SignaturesTest) Validate(encoded string) error {
-	b, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return err
-	}
-
-	segment, err := chainscript.UnmarshalSegment(b)
-	if err != nil {
-		return err
-	}
-
-	err = segment.Validate(context.Background())
-	if err != nil {
-		return err
-	}
-
+// Check the decoded segment.
+func (t *SignaturesTest) Check(segment *chainscript.Segment) error {
 	if len(segment.Link.Signatures) != 2 {
 		return errors.Errorf("invalid number of signatures: %d", len(segment.Link.Signatures))
 	}
 
-	err = segment.Link.Signatures[0].Validate(segment.Link)
+	err := segment.Link.Signatures[0].Validate(segment.Link)
 	if err != nil {
 		return err
 	}
@@ -16,7 +16,6 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"encoding/base64"
 	"fmt"
 
@@ -44,6 +43,16 @@ func (c *CustomData) String() string {
 	return fmt.Sprintf("Name: %s, Age: %d", c.Name, c.Age)
 }
 
+// Description of the segment.
+func (t *SimpleSegmentTest) Description() string {
+	return "A segment containing data and metadata but no references, evidences or signatures."
+}
+
+// ErrorCode returns an empty string: the segment is valid.
+func (t *SimpleSegmentTest) ErrorCode() string {
+	return ""
+}
+
 // Generate encoded segment bytes.
 func (t *SimpleSegmentTest) Generate() string {
 	link, err := chainscript.NewLinkBuilder("test_process", "test_map").
@@ -74,23 +83,8 @@ func (t *SimpleSegmentTest) Generate() string {
 	return base64.StdEncoding.EncodeToString(b)
 }
 
-// Validate encoded segment bytes.
-func (t *SimpleSegmentTest) Validate(encoded string) error {
-	b, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return err
-	}
-
-	segment, err := chainscript.UnmarshalSegment(b)
-	if err != nil {
-		return err
-	}
-
-	err = segment.Validate(context.Background())
-	if err != nil {
-		return err
-	}
-
+// Check the decoded segment.
+func (t *SimpleSegmentTest) Check(segment *chainscript.Segment) error {
 	if segment.Link.Meta.Action != "init" {
 		return errors.Errorf("invalid action: %s", segment.Link.Meta.Action)
 	}
@@ -100,7 +94,7 @@ func (t *SimpleSegmentTest) Validate(encoded string) error {
 	}
 
 	data := CustomData{}
-	err = segment.Link.StructurizeData(&data)
+	err := segment.Link.StructurizeData(&data)
 	if err != nil {
 		return err
 	}
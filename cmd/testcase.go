@@ -14,16 +14,93 @@
 
 package main
 
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/samples"
+)
+
 // TestCase to run accross ChainScript implementations.
+// Test data is validated by the samples package: the segment must be
+// rejected with the test case's error code, or be valid and pass its checks.
 type TestCase interface {
+	samples.Expectation
+
+	// Description of the segment, used to document the test suite.
+	Description() string
 	// Generate encoded segment bytes.
 	Generate() string
-	// Validate encoded segment bytes.
-	Validate(string) error
 }
 
-// TestData associated to a given test.
-type TestData struct {
-	ID   string `json:"id"`
-	Data string `json:"data"`
+// SegmentTest is a test case defined by the segment it generates and either
+// the checks that must pass on the decoded segment or the code of the error
+// expected when validating it.
+type SegmentTest struct {
+	description string
+	generate    func() *chainscript.Segment
+	check       func(*chainscript.Segment) error
+	errorCode   string
+
+	// validationContext optionally returns the context used to validate the
+	// segment.
+	validationContext func(*chainscript.Segment) (context.Context, error)
+}
+
+// Description of the segment.
+func (t *SegmentTest) Description() string {
+	return t.description
+}
+
+// ErrorCode returns the code of the expected validation error.
+func (t *SegmentTest) ErrorCode() string {
+	return t.errorCode
+}
+
+// Generate encoded segment bytes.
+func (t *SegmentTest) Generate() string {
+	return encodeSegment(t.generate())
+}
+
+// Context returns the context used to validate the segment.
+func (t *SegmentTest) Context(ctx context.Context, segment *chainscript.Segment) (context.Context, error) {
+	if t.validationContext == nil {
+		return ctx, nil
+	}
+
+	return t.validationContext(segment)
+}
+
+// Check the decoded segment.
+func (t *SegmentTest) Check(segment *chainscript.Segment) error {
+	if t.check == nil {
+		return nil
+	}
+
+	return t.check(segment)
+}
+
+// mustSegmentify creates a segment from the given link and panics on errors.
+func mustSegmentify(link *chainscript.Link, err error) *chainscript.Segment {
+	if err != nil {
+		panic(err)
+	}
+
+	segment, err := link.Segmentify()
+	if err != nil {
+		panic(err)
+	}
+
+	return segment
+}
+
+// encodeSegment serializes the segment to base64.
+func encodeSegment(segment *chainscript.Segment) string {
+	b, err := chainscript.MarshalSegment(segment)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
 }
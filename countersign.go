@@ -0,0 +1,138 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Countersignature errors.
+var (
+	ErrMissingCountersignTarget = errors.New("countersigned signature doesn't exist")
+	ErrCountersignTargetOrder   = errors.New("countersigned signature must precede the countersignature")
+	ErrInvalidCountersignTarget = errors.New("countersigned signature is invalid")
+)
+
+// countersignTarget matches the signatures selected by a payload path.
+var countersignTarget = regexp.MustCompile(`\bsignatures\[(-?\d+)\]`)
+
+// CountersignPayloadPath returns the payload path of a countersignature of
+// the signatures at the given indexes.
+// The countersignature covers the same parts of the link as the default
+// payload path and the selected signatures.
+func CountersignPayloadPath(targets ...int) string {
+	parts := []string{"version", "data", "meta"}
+	for _, i := range targets {
+		parts = append(parts, fmt.Sprintf("signatures[%d]", i))
+	}
+
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// Countersign signs the link and the given signatures with the current
+// signature version. The countersigned signatures must already be in the
+// link.
+func (l *Link) Countersign(privateKey []byte, targets ...*Signature) error {
+	indexes, err := l.signatureIndexes(targets)
+	if err != nil {
+		return err
+	}
+
+	return l.Sign(privateKey, CountersignPayloadPath(indexes...))
+}
+
+// FindSignature returns the first signature made with the given public key,
+// or nil if there is none. It can be used to select the targets of a
+// countersignature.
+func (l *Link) FindSignature(publicKey []byte) *Signature {
+	for _, s := range l.Signatures {
+		if bytes.Equal(s.PublicKey, publicKey) {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// signatureIndexes returns the indexes of the given signatures in the link.
+func (l *Link) signatureIndexes(targets []*Signature) ([]int, error) {
+	if len(targets) == 0 {
+		return nil, ErrMissingCountersignTarget
+	}
+
+	var indexes []int
+
+targets:
+	for _, target := range targets {
+		for i, s := range l.Signatures {
+			if s == target || (bytes.Equal(s.PublicKey, target.PublicKey) && bytes.Equal(s.Signature, target.Signature)) {
+				indexes = append(indexes, i)
+				continue targets
+			}
+		}
+
+		return nil, ErrMissingCountersignTarget
+	}
+
+	return indexes, nil
+}
+
+// Countersigned returns the indexes of the signatures covered by the
+// signature's payload path. It is empty if the signature isn't a
+// countersignature.
+func (s *Signature) Countersigned() []int {
+	var indexes []int
+	for _, match := range countersignTarget.FindAllStringSubmatch(s.PayloadPath, -1) {
+		i, err := strconv.Atoi(match[1])
+		if err != nil {
+			// Out of range values can't be valid targets.
+			i = -1
+		}
+
+		indexes = append(indexes, i)
+	}
+
+	return indexes
+}
+
+// validateCountersignatures checks that the targets of the countersignatures
+// exist, precede them and are valid.
+// valid contains the result of the verification of each signature.
+func (l *Link) validateCountersignatures(valid []bool, verr *ValidationError) {
+	for i, sig := range l.Signatures {
+		if !valid[i] {
+			continue
+		}
+
+		for _, target := range sig.Countersigned() {
+			field := fmt.Sprintf("signatures[%d]", i)
+
+			switch {
+			case target < 0 || target >= len(l.Signatures):
+				verr.AddError(field, errors.Wrapf(ErrMissingCountersignTarget, "signatures[%d]", target))
+			case target >= i:
+				verr.AddError(field, errors.Wrapf(ErrCountersignTargetOrder, "signatures[%d]", target))
+			case !valid[target]:
+				verr.AddError(field, errors.Wrapf(ErrInvalidCountersignTarget, "signatures[%d]", target))
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountersignPayloadPath(t *testing.T) {
+	assert.Equal(t, "[version,data,meta]", chainscript.CountersignPayloadPath())
+	assert.Equal(t, "[version,data,meta,signatures[0],signatures[2]]", chainscript.CountersignPayloadPath(0, 2))
+}
+
+func TestSignature_Countersigned(t *testing.T) {
+	s := &chainscript.Signature{PayloadPath: "[data,signatures[3],meta.mapId,signatures[-1]]"}
+	assert.Equal(t, []int{3, -1}, s.Countersigned())
+
+	s = &chainscript.Signature{PayloadPath: "[version,data,meta,signatures]"}
+	assert.Empty(t, s.Countersigned())
+}
+
+func TestLink_Countersign(t *testing.T) {
+	ctx := context.Background()
+	alice := chainscripttest.RandomPrivateKey(t)
+	bob := chainscripttest.RandomPrivateKey(t)
+	carol := chainscripttest.RandomPrivateKey(t)
+
+	t.Run("ordered sign-offs", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithRandomData().WithSignatureFromKey(t, alice, "").Build()
+		alicePublicKey := l.Signatures[0].PublicKey
+
+		require.NoError(t, l.Countersign(bob, l.FindSignature(alicePublicKey)))
+		require.NoError(t, l.Countersign(carol, l.Signatures[0], l.Signatures[1]))
+
+		require.Len(t, l.Signatures, 3)
+		assert.Equal(t, []int{0}, l.Signatures[1].Countersigned())
+		assert.Equal(t, []int{0, 1}, l.Signatures[2].Countersigned())
+		assert.NoError(t, l.Validate(ctx))
+	})
+
+	t.Run("modified target", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithSignatureFromKey(t, alice, "").Build()
+		require.NoError(t, l.Countersign(bob, l.Signatures[0]))
+
+		l.Signatures[0].PayloadPath = "[version,data,meta,]"
+		assert.Equal(t, map[string]string{
+			"signatures[0]": chainscript.CodeInvalid,
+			"signatures[1]": chainscript.CodeInvalidSignature,
+		}, problemFields(t, l.Validate(ctx)))
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		require.NoError(t, l.Sign(alice, chainscript.CountersignPayloadPath(3)))
+
+		err := l.Validate(ctx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrMissingCountersignTarget.Error())
+
+		err = l.Countersign(bob, &chainscript.Signature{PublicKey: []byte("unknown")})
+		assert.EqualError(t, err, chainscript.ErrMissingCountersignTarget.Error())
+
+		err = l.Countersign(bob)
+		assert.EqualError(t, err, chainscript.ErrMissingCountersignTarget.Error())
+	})
+
+	t.Run("target after countersignature", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithSignatureFromKey(t, alice, "").Build()
+		require.NoError(t, l.Countersign(bob, l.Signatures[0]))
+
+		// The payload path isn't signed, so the countersignature stays valid
+		// if it is moved before its target and its path updated.
+		aliceSig, bobSig := l.Signatures[0], l.Signatures[1]
+		bobSig.PayloadPath = chainscript.CountersignPayloadPath(1)
+		l.Signatures = []*chainscript.Signature{bobSig, aliceSig}
+
+		err := l.Validate(ctx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrCountersignTargetOrder.Error())
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithInvalidSignature(t).Build()
+		require.NoError(t, l.Countersign(bob, l.Signatures[0]))
+
+		verr, ok := l.Validate(ctx).(*chainscript.ValidationError)
+		require.True(t, ok)
+		require.Len(t, verr.Problems, 2)
+		assert.Equal(t, chainscript.CodeInvalidSignature, verr.Problems[0].Code)
+		assert.Equal(t, "signatures[1]", verr.Problems[1].Field)
+		assert.Equal(t, chainscript.CodeInvalidCountersignTarget, verr.Problems[1].Code)
+	})
+}
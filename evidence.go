@@ -14,6 +14,10 @@
 
 package chainscript
 
+import (
+	"github.com/golang/protobuf/proto"
+)
+
 // NewEvidence creates a new evidence that can be added to a segment.
 func NewEvidence(version, backend, provider string, proofData []byte) (*Evidence, error) {
 	e := &Evidence{
@@ -31,6 +35,11 @@ func NewEvidence(version, backend, provider string, proofData []byte) (*Evidence
 	return e, nil
 }
 
+// Clone returns a deep copy of the evidence.
+func (e *Evidence) Clone() *Evidence {
+	return proto.Clone(e).(*Evidence)
+}
+
 // Validate that the evidence is well-formed.
 // The proof is opaque bytes so it isn't validated here.
 func (e *Evidence) Validate() error {
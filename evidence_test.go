@@ -17,7 +17,9 @@ package chainscript_test
 import (
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -84,3 +86,14 @@ func TestEvidence_New(t *testing.T) {
 		})
 	}
 }
+
+func TestEvidence_Clone(t *testing.T) {
+	e := chainscripttest.RandomEvidence(t)
+
+	clone := e.Clone()
+	assert.True(t, proto.Equal(e, clone))
+	assert.False(t, e == clone)
+
+	clone.Proof[0]++
+	assert.NotEqual(t, e.Proof, clone.Proof)
+}
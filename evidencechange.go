@@ -0,0 +1,207 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Evidence change errors.
+var (
+	ErrEvidenceNotFound      = errors.New("segment doesn't have an evidence for the given backend and provider")
+	ErrEvidenceNotSuperseded = errors.New("new evidence doesn't supersede the existing one")
+)
+
+// PendingProof is implemented by proofs that can be pending (for example a
+// timestamp that isn't anchored yet) before being replaced by a complete
+// proof.
+type PendingProof interface {
+	Proof
+
+	// Pending returns true if the proof isn't complete yet.
+	Pending() bool
+}
+
+// SupersedeCheck checks that a new evidence can replace an existing evidence
+// from the same backend and provider for the given link hash. It returns an
+// error otherwise.
+type SupersedeCheck func(linkHash LinkHash, existing, replacement *Evidence) error
+
+// WithSupersedeCheck returns a context in which evidences of the given
+// backend can only be replaced by evidences that pass the check (see
+// Segment.UpsertEvidence). Backends without a check use
+// DefaultSupersedeCheck.
+func WithSupersedeCheck(ctx context.Context, backend string, check SupersedeCheck) context.Context {
+	checks := map[string]SupersedeCheck{backend: check}
+	for b, c := range supersedeChecksFromContext(ctx) {
+		if _, ok := checks[b]; !ok {
+			checks[b] = c
+		}
+	}
+
+	return context.WithValue(ctx, supersedeChecksKey, checks)
+}
+
+func supersedeChecksFromContext(ctx context.Context) map[string]SupersedeCheck {
+	checks, _ := ctx.Value(supersedeChecksKey).(map[string]SupersedeCheck)
+	return checks
+}
+
+// supersedeCheck returns the context's check for the given backend.
+func supersedeCheck(ctx context.Context, backend string) SupersedeCheck {
+	if check, ok := supersedeChecksFromContext(ctx)[backend]; ok {
+		return check
+	}
+
+	return DefaultSupersedeCheck
+}
+
+// DefaultSupersedeCheck accepts a replacement evidence whose proof verifies
+// the link hash and that has a higher version, or that is a complete proof
+// replacing a pending one (see PendingProof).
+func DefaultSupersedeCheck(linkHash LinkHash, existing, replacement *Evidence) error {
+	if _, ok := replacement.verifiedProof(linkHash); ok {
+		if compareVersions(replacement.Version, existing.Version) > 0 {
+			return nil
+		}
+
+		if pending(existing) && !pending(replacement) {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrEvidenceNotSuperseded, "%s/%s", existing.Backend, existing.Provider)
+}
+
+// pending returns true if the evidence's proof is a pending proof.
+func pending(e *Evidence) bool {
+	proof, err := e.DecodeProof()
+	if err != nil {
+		return false
+	}
+
+	p, ok := proof.(PendingProof)
+	return ok && p.Pending()
+}
+
+// EvidenceAction is the type of change made to the evidences of a segment.
+type EvidenceAction string
+
+// Evidence actions.
+const (
+	EvidenceAdded    EvidenceAction = "added"
+	EvidenceReplaced EvidenceAction = "replaced"
+	EvidenceRemoved  EvidenceAction = "removed"
+)
+
+// EvidenceChange is an audit record of a change to the evidences of a
+// segment.
+type EvidenceChange struct {
+	LinkHash LinkHash
+	Action   EvidenceAction
+	Backend  string
+	Provider string
+
+	// Previous is the replaced or removed evidence.
+	Previous *Evidence
+	// Current is the added or replacement evidence.
+	Current *Evidence
+
+	// Time is the time of the change, given by the context's clock (see
+	// WithClock).
+	Time time.Time
+}
+
+// UpsertEvidence adds an evidence to the segment, or replaces the evidence
+// from the same backend and provider if the new one supersedes it (see
+// WithSupersedeCheck). The segment stores a copy of the evidence.
+// It returns an audit record of the change, or nil if the segment already
+// contains the same evidence.
+func (s *Segment) UpsertEvidence(ctx context.Context, evidence *Evidence) (*EvidenceChange, error) {
+	if err := evidence.Validate(); err != nil {
+		return nil, err
+	}
+
+	evidence = evidence.Clone()
+
+	i := s.evidenceIndex(evidence.Backend, evidence.Provider)
+	if i < 0 {
+		if err := s.AddEvidence(evidence); err != nil {
+			return nil, err
+		}
+
+		return s.evidenceChange(ctx, EvidenceAdded, nil, evidence), nil
+	}
+
+	existing := s.Meta.Evidences[i]
+	if existing.Version == evidence.Version && bytes.Equal(existing.Proof, evidence.Proof) {
+		return nil, nil
+	}
+
+	if err := supersedeCheck(ctx, evidence.Backend)(s.LinkHash(), existing, evidence); err != nil {
+		return nil, err
+	}
+
+	s.Meta.Evidences[i] = evidence
+	return s.evidenceChange(ctx, EvidenceReplaced, existing, evidence), nil
+}
+
+// RemoveEvidence removes the evidence from a provider in a given backend.
+// It returns an audit record of the removal.
+func (s *Segment) RemoveEvidence(ctx context.Context, backend, provider string) (*EvidenceChange, error) {
+	i := s.evidenceIndex(backend, provider)
+	if i < 0 {
+		return nil, errors.Wrapf(ErrEvidenceNotFound, "%s/%s", backend, provider)
+	}
+
+	removed := s.Meta.Evidences[i]
+	s.Meta.Evidences = append(s.Meta.Evidences[:i:i], s.Meta.Evidences[i+1:]...)
+
+	return s.evidenceChange(ctx, EvidenceRemoved, removed, nil), nil
+}
+
+// evidenceIndex returns the index of the evidence from a provider in a given
+// backend, or -1.
+func (s *Segment) evidenceIndex(backend, provider string) int {
+	for i, e := range s.GetMeta().GetEvidences() {
+		if e.Backend == backend && e.Provider == provider {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Segment) evidenceChange(ctx context.Context, action EvidenceAction, previous, current *Evidence) *EvidenceChange {
+	c := &EvidenceChange{
+		LinkHash: s.LinkHash(),
+		Action:   action,
+		Previous: previous,
+		Current:  current,
+		Time:     now(ctx),
+	}
+
+	if e := current; e != nil {
+		c.Backend, c.Provider = e.Backend, e.Provider
+	} else {
+		c.Backend, c.Provider = previous.Backend, previous.Provider
+	}
+
+	return c
+}
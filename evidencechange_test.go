@@ -0,0 +1,210 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pendingBackend decodes proofs whose first byte is 0 as pending proofs.
+const pendingBackend = "pending-test"
+
+type pendingProof bool
+
+func (p pendingProof) Time() uint64 { return 0 }
+
+func (p pendingProof) Verify(interface{}) bool { return true }
+
+func (p pendingProof) Pending() bool { return bool(p) }
+
+func init() {
+	chainscript.RegisterProofDecoder(pendingBackend, func(e *chainscript.Evidence) (chainscript.Proof, error) {
+		return pendingProof(e.Proof[0] == 0), nil
+	})
+}
+
+func TestSegment_UpsertEvidence(t *testing.T) {
+	changedAt := time.Unix(1500000000, 0)
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return changedAt })
+
+	evidence := func(backend, version string, proof ...byte) *chainscript.Evidence {
+		return &chainscript.Evidence{Version: version, Backend: backend, Provider: "main", Proof: proof}
+	}
+
+	t.Run("add", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		e := evidence("btc", "1.0.0", 1)
+
+		c, err := s.UpsertEvidence(ctx, e)
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.Equal(t, chainscript.EvidenceAdded, c.Action)
+		assert.Equal(t, s.LinkHash(), c.LinkHash)
+		assert.Equal(t, "btc", c.Backend)
+		assert.Equal(t, "main", c.Provider)
+		assert.Nil(t, c.Previous)
+		assert.Equal(t, e, c.Current)
+		assert.Equal(t, changedAt, c.Time)
+		assert.Equal(t, e, s.GetEvidence("btc", "main"))
+	})
+
+	t.Run("invalid evidence", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		_, err := s.UpsertEvidence(ctx, evidence("btc", "1.0.0"))
+		assert.EqualError(t, err, chainscript.ErrMissingProof.Error())
+	})
+
+	t.Run("same evidence", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, s.AddEvidence(evidence("btc", "1.0.0", 1)))
+
+		c, err := s.UpsertEvidence(ctx, evidence("btc", "1.0.0", 1))
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+
+	t.Run("higher version", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		old := evidence(pendingBackend, "1.0.0", 1)
+		require.NoError(t, s.AddEvidence(old))
+		require.NoError(t, s.AddEvidence(evidence("eth", "1.0.0", 1)))
+
+		_, err := s.UpsertEvidence(ctx, evidence(pendingBackend, "0.9.0", 2))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+
+		_, err = s.UpsertEvidence(ctx, evidence(pendingBackend, "1.0.0", 2))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+
+		e := evidence(pendingBackend, "1.1.0", 2)
+		c, err := s.UpsertEvidence(ctx, e)
+		require.NoError(t, err)
+		assert.Equal(t, chainscript.EvidenceReplaced, c.Action)
+		assert.Equal(t, old, c.Previous)
+		assert.Equal(t, e, c.Current)
+
+		require.Len(t, s.Meta.Evidences, 2)
+		assert.Equal(t, e, s.Meta.Evidences[0], "replaced in place")
+	})
+
+	t.Run("pending to complete proof", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, s.AddEvidence(evidence(pendingBackend, "1.0.0", 0)))
+
+		// Another pending proof doesn't supersede the pending one.
+		_, err := s.UpsertEvidence(ctx, evidence(pendingBackend, "1.0.0", 0, 1))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+
+		c, err := s.UpsertEvidence(ctx, evidence(pendingBackend, "1.0.0", 1))
+		require.NoError(t, err)
+		assert.Equal(t, chainscript.EvidenceReplaced, c.Action)
+
+		// A complete proof can't be replaced by a pending one.
+		_, err = s.UpsertEvidence(ctx, evidence(pendingBackend, "1.0.0", 0))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+	})
+
+	t.Run("unverified replacement", func(t *testing.T) {
+		p := chainscript.NewLocalTimestampProvider("main", chainscripttest.RandomPrivateKey(t))
+		s := chainscripttest.RandomSegment(t)
+
+		evidences, err := p.Submit(ctx, []chainscript.LinkHash{s.LinkHash()})
+		require.NoError(t, err)
+		require.NoError(t, s.AddEvidence(evidences[s.LinkHash().String()]))
+
+		// A newer proof of another link hash.
+		other := chainscripttest.RandomHash()
+		forged, err := p.Submit(ctx, []chainscript.LinkHash{other})
+		require.NoError(t, err)
+		e := forged[other.String()]
+		e.Version = "2.0.0"
+
+		_, err = s.UpsertEvidence(ctx, e)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+
+		// Undecodable proofs don't supersede either.
+		_, err = s.UpsertEvidence(ctx, evidence(chainscript.LocalTimestampBackend, "2.0.0", 1))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+	})
+
+	t.Run("context check", func(t *testing.T) {
+		backend := "btc"
+		ctx := chainscript.WithSupersedeCheck(ctx, backend, func(_ chainscript.LinkHash, existing, replacement *chainscript.Evidence) error {
+			if replacement.Proof[0] > existing.Proof[0] {
+				return nil
+			}
+			return chainscript.ErrEvidenceNotSuperseded
+		})
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, s.AddEvidence(evidence(backend, "1.0.0", 2)))
+
+		_, err := s.UpsertEvidence(ctx, evidence(backend, "2.0.0", 1))
+		assert.EqualError(t, err, chainscript.ErrEvidenceNotSuperseded.Error())
+
+		_, err = s.UpsertEvidence(ctx, evidence(backend, "1.0.0", 3))
+		assert.NoError(t, err)
+
+		// Other contexts use the default check.
+		_, err = s.UpsertEvidence(context.Background(), evidence(backend, "1.0.0", 4))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+	})
+
+	t.Run("copies the evidence", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		e := evidence("btc", "1.0.0", 1)
+
+		_, err := s.UpsertEvidence(ctx, e)
+		require.NoError(t, err)
+
+		e.Proof[0] = 2
+		assert.Equal(t, []byte{1}, s.GetEvidence("btc", "main").Proof)
+	})
+}
+
+func TestSegment_RemoveEvidence(t *testing.T) {
+	removedAt := time.Unix(1500000000, 0)
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return removedAt })
+
+	s := chainscripttest.RandomSegment(t)
+	e1, e2 := chainscripttest.RandomEvidence(t), chainscripttest.RandomEvidence(t)
+	require.NoError(t, s.AddEvidence(e1))
+	require.NoError(t, s.AddEvidence(e2))
+
+	_, err := s.RemoveEvidence(ctx, e1.Backend, "unknown")
+	assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotFound.Error())
+
+	c, err := s.RemoveEvidence(ctx, e1.Backend, e1.Provider)
+	require.NoError(t, err)
+	assert.Equal(t, chainscript.EvidenceRemoved, c.Action)
+	assert.Equal(t, e1.Backend, c.Backend)
+	assert.Equal(t, e1.Provider, c.Provider)
+	assert.Equal(t, e1, c.Previous)
+	assert.Nil(t, c.Current)
+	assert.Equal(t, removedAt, c.Time)
+
+	require.Len(t, s.Meta.Evidences, 1)
+	assert.Equal(t, e2, s.Meta.Evidences[0])
+
+	// The evidence can be added again.
+	assert.NoError(t, s.AddEvidence(e1))
+}
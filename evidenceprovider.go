@@ -0,0 +1,343 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EvidenceProvider produces evidences (for example blockchain anchors or
+// trusted timestamps) for link hashes.
+type EvidenceProvider interface {
+	// Backend returns the backend of the produced evidences.
+	Backend() string
+	// Provider returns the provider of the produced evidences.
+	Provider() string
+
+	// Submit asks for evidences of the given link hashes. Providers that
+	// produce evidences synchronously return them, indexed by
+	// LinkHash.String(). The other evidences are obtained with Poll.
+	Submit(ctx context.Context, linkHashes []LinkHash) (map[string]*Evidence, error)
+
+	// Poll returns the evidences that are ready for the given submitted link
+	// hashes, indexed by LinkHash.String(). Pending proofs (see PendingProof)
+	// are ignored: their link hashes are polled again.
+	Poll(ctx context.Context, linkHashes []LinkHash) (map[string]*Evidence, error)
+}
+
+// Batcher defaults.
+const (
+	DefaultBatchSize    = 100
+	DefaultBatchDelay   = time.Second
+	DefaultPollInterval = time.Second
+	DefaultRetries      = 3
+	DefaultRetryDelay   = 100 * time.Millisecond
+)
+
+// BatcherOptions configure a Batcher.
+type BatcherOptions struct {
+	// MaxSize is the number of pending segments that triggers a submission.
+	// Defaults to DefaultBatchSize.
+	MaxSize int
+	// MaxDelay is the longest time a segment waits before being submitted.
+	// Defaults to DefaultBatchDelay.
+	MaxDelay time.Duration
+	// PollInterval is the delay between two polls for evidences that weren't
+	// returned at submission. Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// Retries is the number of retries of failed submissions and polls.
+	// Defaults to DefaultRetries. A negative value disables retries.
+	Retries int
+	// RetryDelay is the delay before the first retry. It doubles after each
+	// retry. Defaults to DefaultRetryDelay.
+	RetryDelay time.Duration
+
+	// OnEvidence is called when an evidence is added to a segment or
+	// replaces one of its evidences (see Segment.UpsertEvidence).
+	OnEvidence func(s *Segment, e *Evidence)
+	// OnError is called with the segments that can't get an evidence.
+	// Failed polls are reported without segments: the waiting segments are
+	// polled again at the next interval.
+	OnError func(segments []*Segment, err error)
+}
+
+// Batcher accumulates segments, submits their link hashes to an evidence
+// provider and adds the resulting evidences to the segments.
+// Segments shouldn't be modified between the time they are added and the
+// time their evidence (or error) is reported.
+type Batcher struct {
+	provider EvidenceProvider
+	opts     BatcherOptions
+
+	mu      sync.Mutex
+	pending []*Segment
+	timer   *time.Timer
+	ready   chan struct{}
+
+	// work serializes submissions and polls, and protects waiting.
+	work    sync.Mutex
+	waiting map[string][]*Segment
+}
+
+// NewBatcher creates a batcher for the given provider.
+// Batches are submitted by Run or Flush.
+func NewBatcher(provider EvidenceProvider, opts *BatcherOptions) *Batcher {
+	b := &Batcher{
+		provider: provider,
+		ready:    make(chan struct{}, 1),
+		waiting:  make(map[string][]*Segment),
+	}
+
+	if opts != nil {
+		b.opts = *opts
+	}
+	if b.opts.MaxSize <= 0 {
+		b.opts.MaxSize = DefaultBatchSize
+	}
+	if b.opts.MaxDelay <= 0 {
+		b.opts.MaxDelay = DefaultBatchDelay
+	}
+	if b.opts.PollInterval <= 0 {
+		b.opts.PollInterval = DefaultPollInterval
+	}
+	if b.opts.Retries == 0 {
+		b.opts.Retries = DefaultRetries
+	}
+	if b.opts.RetryDelay <= 0 {
+		b.opts.RetryDelay = DefaultRetryDelay
+	}
+
+	return b
+}
+
+// Add adds a segment to the next batch.
+func (b *Batcher) Add(s *Segment) error {
+	if len(s.LinkHash()) == 0 {
+		return ErrMissingLinkHash
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, s)
+	if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.opts.MaxDelay, b.signal)
+	}
+	if len(b.pending) >= b.opts.MaxSize {
+		b.signal()
+	}
+
+	return nil
+}
+
+// signal notifies Run that a batch is ready.
+func (b *Batcher) signal() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Run submits batches when they reach the size or time threshold, and polls
+// for evidences until the context is done.
+// Errors are reported to BatcherOptions.OnError.
+func (b *Batcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(b.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.ready:
+			_ = b.submit(ctx)
+		case <-ticker.C:
+			_ = b.poll(ctx)
+		}
+	}
+}
+
+// Flush submits the pending segments and waits until every submitted segment
+// has an evidence that isn't pending. It returns the first error, which is also reported to
+// BatcherOptions.OnError.
+func (b *Batcher) Flush(ctx context.Context) error {
+	if err := b.submit(ctx); err != nil {
+		return err
+	}
+
+	for {
+		if err := b.poll(ctx); err != nil {
+			return err
+		}
+
+		b.work.Lock()
+		done := len(b.waiting) == 0
+		b.work.Unlock()
+
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.opts.PollInterval):
+		}
+	}
+}
+
+// submit submits the pending segments.
+func (b *Batcher) submit(ctx context.Context) error {
+	b.work.Lock()
+	defer b.work.Unlock()
+
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	linkHashes := make([]LinkHash, len(batch))
+	for i, s := range batch {
+		linkHashes[i] = s.LinkHash()
+	}
+
+	var evidences map[string]*Evidence
+	err := b.retry(ctx, func() (err error) {
+		evidences, err = b.provider.Submit(ctx, linkHashes)
+		return err
+	})
+	if err != nil {
+		b.fail(batch, err)
+		return err
+	}
+
+	var firstErr error
+	for _, s := range batch {
+		lh := s.LinkHash().String()
+		if e, ok := evidences[lh]; ok {
+			if err := b.addEvidence(ctx, s, e); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			// Pending proofs are replaced by the complete proofs returned
+			// by Poll.
+			if !pending(e) {
+				continue
+			}
+		}
+
+		b.waiting[lh] = append(b.waiting[lh], s)
+	}
+
+	return firstErr
+}
+
+// poll adds the evidences that are ready to the waiting segments.
+func (b *Batcher) poll(ctx context.Context) error {
+	b.work.Lock()
+	defer b.work.Unlock()
+
+	if len(b.waiting) == 0 {
+		return nil
+	}
+
+	linkHashes := make([]LinkHash, 0, len(b.waiting))
+	for _, segments := range b.waiting {
+		linkHashes = append(linkHashes, segments[0].LinkHash())
+	}
+
+	var evidences map[string]*Evidence
+	err := b.retry(ctx, func() (err error) {
+		evidences, err = b.provider.Poll(ctx, linkHashes)
+		return err
+	})
+	if err != nil {
+		// The waiting segments will be polled again.
+		b.fail(nil, err)
+		return err
+	}
+
+	var firstErr error
+	for lh, e := range evidences {
+		segments, ok := b.waiting[lh]
+		if !ok || pending(e) {
+			continue
+		}
+
+		delete(b.waiting, lh)
+		for _, s := range segments {
+			if err := b.addEvidence(ctx, s, e); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// addEvidence adds a copy of the evidence to the segment, or replaces the
+// evidence it supersedes (for example a pending proof).
+func (b *Batcher) addEvidence(ctx context.Context, s *Segment, e *Evidence) error {
+	change, err := s.UpsertEvidence(ctx, e)
+	if err != nil {
+		b.fail([]*Segment{s}, err)
+		return err
+	}
+
+	if change != nil && b.opts.OnEvidence != nil {
+		b.opts.OnEvidence(s, change.Current)
+	}
+
+	return nil
+}
+
+func (b *Batcher) fail(segments []*Segment, err error) {
+	if b.opts.OnError != nil {
+		b.opts.OnError(segments, err)
+	}
+}
+
+// retry calls fn until it succeeds, the retries are exhausted or the context
+// is done.
+func (b *Batcher) retry(ctx context.Context, fn func() error) error {
+	delay := b.opts.RetryDelay
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || i >= b.opts.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asyncProvider returns time evidences after a number of polls, and can fail
+// the first calls.
+type asyncProvider struct {
+	mu          sync.Mutex
+	submitted   [][]chainscript.LinkHash
+	polls       int
+	readyAfter  int
+	submitFails int
+	pollFails   int
+}
+
+func (p *asyncProvider) Backend() string  { return timeBackend }
+func (p *asyncProvider) Provider() string { return "async" }
+
+func (p *asyncProvider) Submit(_ context.Context, linkHashes []chainscript.LinkHash) (map[string]*chainscript.Evidence, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.submitFails > 0 {
+		p.submitFails--
+		return nil, errors.New("submit failed")
+	}
+
+	p.submitted = append(p.submitted, linkHashes)
+	return nil, nil
+}
+
+func (p *asyncProvider) Poll(_ context.Context, linkHashes []chainscript.LinkHash) (map[string]*chainscript.Evidence, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pollFails > 0 {
+		p.pollFails--
+		return nil, errors.New("poll failed")
+	}
+
+	p.polls++
+	if p.polls < p.readyAfter {
+		return nil, nil
+	}
+
+	evidences := make(map[string]*chainscript.Evidence)
+	for _, lh := range linkHashes {
+		e := timeEvidence(time.Now())
+		e.Provider = p.Provider()
+		evidences[lh.String()] = e
+	}
+
+	return evidences, nil
+}
+
+// pendingProvider returns pending proofs at submission and on the first
+// poll, and complete proofs afterwards.
+type pendingProvider struct {
+	polls int
+}
+
+func (p *pendingProvider) Backend() string  { return pendingBackend }
+func (p *pendingProvider) Provider() string { return "main" }
+
+func (p *pendingProvider) evidences(linkHashes []chainscript.LinkHash, proof byte) map[string]*chainscript.Evidence {
+	evidences := make(map[string]*chainscript.Evidence)
+	for _, lh := range linkHashes {
+		evidences[lh.String()] = &chainscript.Evidence{
+			Version:  "1.0.0",
+			Backend:  p.Backend(),
+			Provider: p.Provider(),
+			Proof:    []byte{proof},
+		}
+	}
+
+	return evidences
+}
+
+func (p *pendingProvider) Submit(_ context.Context, linkHashes []chainscript.LinkHash) (map[string]*chainscript.Evidence, error) {
+	return p.evidences(linkHashes, 0), nil
+}
+
+func (p *pendingProvider) Poll(_ context.Context, linkHashes []chainscript.LinkHash) (map[string]*chainscript.Evidence, error) {
+	p.polls++
+	if p.polls < 2 {
+		return p.evidences(linkHashes, 0), nil
+	}
+
+	return p.evidences(linkHashes, 1), nil
+}
+
+// batchRecorder records the callbacks of a batcher.
+type batchRecorder struct {
+	mu        sync.Mutex
+	evidences map[string]*chainscript.Evidence
+	failed    []*chainscript.Segment
+	done      chan struct{}
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{
+		evidences: make(map[string]*chainscript.Evidence),
+		done:      make(chan struct{}, 100),
+	}
+}
+
+func (r *batchRecorder) options(opts *chainscript.BatcherOptions) *chainscript.BatcherOptions {
+	opts.OnEvidence = func(s *chainscript.Segment, e *chainscript.Evidence) {
+		r.mu.Lock()
+		r.evidences[s.LinkHash().String()] = e
+		r.mu.Unlock()
+		r.done <- struct{}{}
+	}
+	opts.OnError = func(segments []*chainscript.Segment, _ error) {
+		r.mu.Lock()
+		r.failed = append(r.failed, segments...)
+		r.mu.Unlock()
+	}
+
+	return opts
+}
+
+func (r *batchRecorder) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.done:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for evidences")
+		}
+	}
+}
+
+func TestBatcher_Flush(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("synchronous provider", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{}))
+
+		segments := []*chainscript.Segment{chainscripttest.RandomSegment(t), chainscripttest.RandomSegment(t)}
+		for _, s := range segments {
+			require.NoError(t, b.Add(s))
+		}
+
+		require.NoError(t, b.Flush(ctx))
+		for _, s := range segments {
+			e := s.GetEvidence(chainscript.LocalTimestampBackend, "local")
+			require.NotNil(t, e)
+			assert.Equal(t, e, rec.evidences[s.LinkHash().String()])
+		}
+	})
+
+	t.Run("polled evidences", func(t *testing.T) {
+		p := &asyncProvider{readyAfter: 3}
+		b := chainscript.NewBatcher(p, &chainscript.BatcherOptions{PollInterval: time.Millisecond})
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(ctx))
+
+		assert.NotNil(t, s.GetEvidence(timeBackend, "async"))
+		assert.Equal(t, 3, p.polls)
+	})
+
+	t.Run("missing link hash", func(t *testing.T) {
+		b := chainscript.NewBatcher(&asyncProvider{}, nil)
+		err := b.Add(&chainscript.Segment{Link: chainscripttest.RandomLink(t)})
+		assert.EqualError(t, err, chainscript.ErrMissingLinkHash.Error())
+	})
+
+	t.Run("same evidence", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{}))
+		clockCtx := chainscript.WithClock(ctx, func() time.Time { return time.Unix(1500000000, 0) })
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(clockCtx))
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(clockCtx))
+
+		assert.Len(t, s.Meta.Evidences, 1)
+		assert.Len(t, rec.done, 1, "evidence reported once")
+	})
+
+	t.Run("evidence not superseded", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{}))
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(ctx))
+		require.NoError(t, b.Add(s))
+
+		// A later timestamp doesn't replace the first one.
+		later := chainscript.WithClock(ctx, func() time.Time { return time.Now().Add(time.Hour) })
+		err := b.Flush(later)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceNotSuperseded.Error())
+		assert.Equal(t, []*chainscript.Segment{s}, rec.failed)
+	})
+
+	t.Run("pending proof replaced", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := &pendingProvider{}
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{}))
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(ctx))
+
+		require.Len(t, s.Meta.Evidences, 1)
+		assert.Equal(t, []byte{1}, s.Meta.Evidences[0].Proof)
+		assert.Equal(t, 2, p.polls, "polled until the proof is complete")
+		assert.Len(t, rec.done, 2)
+		assert.Empty(t, rec.failed)
+	})
+}
+
+func TestBatcher_Retries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("submission retried", func(t *testing.T) {
+		p := &asyncProvider{submitFails: 2, readyAfter: 1}
+		b := chainscript.NewBatcher(p, &chainscript.BatcherOptions{
+			Retries:    2,
+			RetryDelay: time.Millisecond,
+		})
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(ctx))
+		assert.NotNil(t, s.GetEvidence(timeBackend, "async"))
+	})
+
+	t.Run("submission failed", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := &asyncProvider{submitFails: 2}
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{
+			Retries:    1,
+			RetryDelay: time.Millisecond,
+		}))
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		assert.EqualError(t, b.Flush(ctx), "submit failed")
+		assert.Equal(t, []*chainscript.Segment{s}, rec.failed)
+		assert.Empty(t, s.Meta.Evidences)
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		p := &asyncProvider{submitFails: 1}
+		b := chainscript.NewBatcher(p, &chainscript.BatcherOptions{Retries: -1})
+
+		require.NoError(t, b.Add(chainscripttest.RandomSegment(t)))
+		assert.EqualError(t, b.Flush(ctx), "submit failed")
+	})
+
+	t.Run("poll retried", func(t *testing.T) {
+		p := &asyncProvider{pollFails: 1, readyAfter: 1}
+		b := chainscript.NewBatcher(p, &chainscript.BatcherOptions{RetryDelay: time.Millisecond})
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(ctx))
+		assert.NotNil(t, s.GetEvidence(timeBackend, "async"))
+	})
+
+	t.Run("poll failed", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := &asyncProvider{pollFails: 2, readyAfter: 1}
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{
+			Retries:    1,
+			RetryDelay: time.Millisecond,
+		}))
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		assert.EqualError(t, b.Flush(ctx), "poll failed")
+		assert.Empty(t, rec.failed, "failed polls aren't reported per segment")
+
+		// The segment is still waiting.
+		require.NoError(t, b.Flush(ctx))
+		assert.NotNil(t, s.GetEvidence(timeBackend, "async"))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		p := &asyncProvider{submitFails: 1}
+		b := chainscript.NewBatcher(p, &chainscript.BatcherOptions{RetryDelay: time.Hour})
+
+		require.NoError(t, b.Add(chainscripttest.RandomSegment(t)))
+		assert.EqualError(t, b.Flush(canceled), "submit failed")
+	})
+}
+
+func TestBatcher_Run(t *testing.T) {
+	run := func(t *testing.T, b *chainscript.Batcher) context.CancelFunc {
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() { errs <- b.Run(ctx) }()
+
+		return func() {
+			cancel()
+			assert.EqualError(t, <-errs, context.Canceled.Error())
+		}
+	}
+
+	t.Run("size threshold", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := &asyncProvider{readyAfter: 1}
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{
+			MaxSize:      2,
+			MaxDelay:     time.Hour,
+			PollInterval: time.Millisecond,
+		}))
+		stop := run(t, b)
+		defer stop()
+
+		require.NoError(t, b.Add(chainscripttest.RandomSegment(t)))
+		require.NoError(t, b.Add(chainscripttest.RandomSegment(t)))
+		rec.wait(t, 2)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		require.Len(t, p.submitted, 1)
+		assert.Len(t, p.submitted[0], 2)
+	})
+
+	t.Run("time threshold", func(t *testing.T) {
+		rec := newBatchRecorder()
+		p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+		b := chainscript.NewBatcher(p, rec.options(&chainscript.BatcherOptions{
+			MaxDelay: 10 * time.Millisecond,
+		}))
+		stop := run(t, b)
+		defer stop()
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		rec.wait(t, 1)
+	})
+}
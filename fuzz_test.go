@@ -0,0 +1,152 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package chainscript_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stratumn/go-chainscript/samples"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run the fuzz targets with go test -fuzz=<target>.
+// Without -fuzz, only the seed corpus is checked.
+
+// seeds adds a few generator seeds to the corpus.
+func seeds(f *testing.F) {
+	for _, seed := range []int64{0, 1, 42, -7, 1 << 40} {
+		f.Add(seed)
+	}
+}
+
+func FuzzUnmarshalSegment(f *testing.F) {
+	all, err := samples.Load("./proto/samples/1.0.0.json")
+	require.NoError(f, err)
+	for _, s := range all {
+		b, err := base64.StdEncoding.DecodeString(s.Data)
+		require.NoError(f, err)
+		f.Add(b)
+	}
+
+	for _, seed := range []int64{0, 1, 42} {
+		b, err := chainscript.MarshalSegment(chainscripttest.NewGenerator(seed).Segment())
+		require.NoError(f, err)
+		f.Add(b)
+	}
+
+	// Malformed segments that used to cause nil pointer exceptions.
+	for _, s := range []*chainscript.Segment{
+		{},
+		{Link: &chainscript.Link{}},
+		{Link: &chainscript.Link{Version: chainscript.LinkVersion, Meta: &chainscript.LinkMeta{}}},
+		{Meta: &chainscript.SegmentMeta{Evidences: []*chainscript.Evidence{nil}}},
+	} {
+		b, err := chainscript.MarshalSegment(s)
+		require.NoError(f, err)
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s, err := chainscript.UnmarshalSegment(b)
+		if err != nil {
+			return
+		}
+
+		// Malformed content must be rejected without panicking.
+		_ = s.Validate(context.Background())
+		_ = s.LinkHash()
+		_ = s.Clone()
+
+		_, err = chainscript.MarshalSegment(s)
+		assert.NoError(t, err)
+	})
+}
+
+func FuzzSegmentRoundTrip(f *testing.F) {
+	seeds(f)
+
+	f.Fuzz(func(t *testing.T, seed int64) {
+		s := chainscripttest.NewGenerator(seed).Segment()
+		require.NoError(t, s.Validate(context.Background()))
+
+		b, err := chainscript.MarshalSegment(s)
+		require.NoError(t, err)
+
+		s2, err := chainscript.UnmarshalSegment(b)
+		require.NoError(t, err)
+
+		assert.Equal(t, s.LinkHash(), s2.LinkHash())
+		lh, err := s2.Link.Hash()
+		require.NoError(t, err)
+		assert.Equal(t, s.LinkHash(), lh)
+
+		chainscripttest.SegmentsEqual(t, s, s2)
+	})
+}
+
+func FuzzDataRoundTrip(f *testing.F) {
+	seeds(f)
+
+	f.Fuzz(func(t *testing.T, seed int64) {
+		g := chainscripttest.NewGenerator(seed)
+		data, metadata := g.Data(), g.Data()
+
+		l := &chainscript.Link{
+			Version: chainscript.LinkVersion,
+			Meta:    &chainscript.LinkMeta{ClientId: chainscript.ClientID},
+		}
+		require.NoError(t, l.SetData(data))
+		require.NoError(t, l.SetMetadata(metadata))
+
+		var decoded interface{}
+		require.NoError(t, l.StructurizeData(&decoded))
+		assert.Equal(t, data, decoded)
+
+		var decodedMetadata interface{}
+		require.NoError(t, l.StructurizeMetadata(&decodedMetadata))
+		assert.Equal(t, metadata, decodedMetadata)
+	})
+}
+
+func FuzzClone(f *testing.F) {
+	seeds(f)
+
+	f.Fuzz(func(t *testing.T, seed int64) {
+		s := chainscripttest.NewGenerator(seed).Segment()
+
+		l, err := s.Link.Clone()
+		require.NoError(t, err)
+		lh, err := l.Hash()
+		require.NoError(t, err)
+		assert.Equal(t, s.LinkHash(), lh)
+
+		clone := s.Clone()
+		chainscripttest.SegmentsEqual(t, s, clone)
+
+		// The clone is independent from the original.
+		clone.Link.Meta.Action += "modified"
+		lh, err = s.Link.Hash()
+		require.NoError(t, err)
+		assert.Equal(t, s.LinkHash(), lh)
+	})
+}
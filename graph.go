@@ -0,0 +1,385 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Graph errors.
+var (
+	ErrReverseResolverRequired = errors.New("a reverse resolver is required to follow edges to children or referencing links")
+	ErrUnrelatedSegment        = errors.New("resolved segment doesn't point to the requested link")
+
+	// ErrSkipSegment can be returned by a WalkFunc to avoid following the edges
+	// of the current segment. It is not returned as an error by Walk.
+	ErrSkipSegment = errors.New("skip this segment")
+)
+
+// Edges selects which edges of the link graph are followed during a walk.
+type Edges int
+
+// Edges of the link graph.
+const (
+	// EdgeParent follows meta.prevLinkHash.
+	EdgeParent Edges = 1 << iota
+	// EdgeChildren follows links whose parent is the current link.
+	EdgeChildren
+	// EdgeRefs follows meta.refs (potentially to other processes).
+	EdgeRefs
+	// EdgeReferencedBy follows links that reference the current link.
+	EdgeReferencedBy
+
+	// EdgeAll follows every edge.
+	EdgeAll = EdgeParent | EdgeChildren | EdgeRefs | EdgeReferencedBy
+)
+
+// WalkOptions configure a walk of the link graph.
+type WalkOptions struct {
+	// Edges to follow. Defaults to EdgeAll.
+	Edges Edges
+	// MaxDepth is the maximum distance from the starting segment.
+	// Zero means no limit.
+	MaxDepth int
+}
+
+// WalkFunc is called for every segment visited by Walk, with its distance to
+// the starting segment.
+type WalkFunc func(s *Segment, depth int) error
+
+// Walk visits the link graph breadth-first, starting from the given segment.
+// Each segment is visited once. Links that can't be resolved are skipped
+// (see DanglingRefs to detect them).
+// It returns ErrLinkHashMismatch if a segment's link hash isn't the hash of
+// its link, and ErrUnrelatedSegment if the resolver returns children or
+// referencing segments that don't point to the current segment.
+// Following children or referencing links requires a ReverseResolver.
+func Walk(ctx context.Context, r Resolver, start *Segment, opts *WalkOptions, fn WalkFunc) error {
+	edges := EdgeAll
+	maxDepth := 0
+	if opts != nil {
+		if opts.Edges != 0 {
+			edges = opts.Edges
+		}
+		maxDepth = opts.MaxDepth
+	}
+
+	rr, reverse := r.(ReverseResolver)
+	if !reverse && edges&(EdgeChildren|EdgeReferencedBy) != 0 {
+		return ErrReverseResolverRequired
+	}
+
+	startHash, err := resolvedLinkHash(start)
+	if err != nil {
+		return err
+	}
+
+	// Segments are checked, so their link hash is the hash of their link and
+	// can't be spoofed to hide or duplicate segments.
+	visited := map[string]struct{}{startHash.String(): struct{}{}}
+	current := []*Segment{start}
+
+	for depth := 0; len(current) > 0; depth++ {
+		var next []*Segment
+		for _, s := range current {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			err := fn(s, depth)
+			if err == ErrSkipSegment {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if maxDepth > 0 && depth >= maxDepth {
+				continue
+			}
+
+			neighbours, err := neighbours(ctx, r, rr, s, edges)
+			if err != nil {
+				return err
+			}
+
+			for _, n := range neighbours {
+				lh := n.LinkHash().String()
+				if _, ok := visited[lh]; ok {
+					continue
+				}
+
+				visited[lh] = struct{}{}
+				next = append(next, n)
+			}
+		}
+
+		current = next
+	}
+
+	return nil
+}
+
+// neighbours returns the segments connected to s by the given edges.
+func neighbours(ctx context.Context, r Resolver, rr ReverseResolver, s *Segment, edges Edges) ([]*Segment, error) {
+	var results []*Segment
+
+	if edges&EdgeParent != 0 {
+		if prev := s.Link.PrevLinkHash(); len(prev) > 0 {
+			parent, err := resolve(ctx, r, prev)
+			if err != nil {
+				return nil, err
+			}
+			if parent != nil {
+				results = append(results, parent)
+			}
+		}
+	}
+
+	if edges&EdgeChildren != 0 {
+		children, err := childrenOf(ctx, rr, s)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, children...)
+	}
+
+	if edges&EdgeRefs != 0 {
+		referenced, err := Referenced(ctx, r, s)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, referenced...)
+	}
+
+	if edges&EdgeReferencedBy != 0 {
+		referencing, err := ReferencedBy(ctx, rr, s)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, referencing...)
+	}
+
+	return results, nil
+}
+
+// resolve returns the segment containing the given link, or nil if it can't
+// be found.
+func resolve(ctx context.Context, r Resolver, linkHash LinkHash) (*Segment, error) {
+	s, err := r.Resolve(ctx, linkHash)
+	if errors.Cause(err) == ErrSegmentNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResolved(s, linkHash); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// checkResolved checks that the link of a resolved segment hashes to the
+// requested link hash, so that resolvers can't return a different link.
+func checkResolved(s *Segment, linkHash LinkHash) error {
+	lh, err := resolvedLinkHash(s)
+	if err != nil {
+		return errors.Wrap(err, linkHash.String())
+	}
+
+	if !bytes.Equal(lh, linkHash) {
+		return errors.Wrap(ErrLinkHashMismatch, linkHash.String())
+	}
+
+	return nil
+}
+
+// checkReverseResolved checks that the links of segments returned by a
+// ReverseResolver hash to their segment's link hash and point to the given
+// link, so that resolvers can't invent children or references.
+func checkReverseResolved(segments []*Segment, linkHash LinkHash, points func(*Link, LinkHash) bool) error {
+	for _, s := range segments {
+		lh, err := resolvedLinkHash(s)
+		if err != nil {
+			return errors.Wrap(err, linkHash.String())
+		}
+
+		if !points(s.Link, linkHash) {
+			return errors.Wrapf(ErrUnrelatedSegment, "%s doesn't point to %s", lh.String(), linkHash.String())
+		}
+	}
+
+	return nil
+}
+
+// resolvedLinkHash hashes the link of a resolved segment and checks that it
+// is the segment's link hash.
+func resolvedLinkHash(s *Segment) (LinkHash, error) {
+	if s == nil || s.Link == nil {
+		return nil, ErrMissingLink
+	}
+
+	lh, err := s.Link.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(lh, s.LinkHash()) {
+		return nil, ErrLinkHashMismatch
+	}
+
+	return lh, nil
+}
+
+// isChild returns whether the link's parent is the given link.
+func isChild(l *Link, parent LinkHash) bool {
+	return bytes.Equal(l.PrevLinkHash(), parent)
+}
+
+// isReferencing returns whether the link references the given link.
+func isReferencing(l *Link, linkHash LinkHash) bool {
+	for _, ref := range l.GetMeta().GetRefs() {
+		if bytes.Equal(ref.LinkHash, linkHash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ancestors returns the segment's ancestors, from its parent to the first
+// link of its map.
+// It returns ErrSegmentNotFound if an ancestor can't be resolved and
+// ErrLinkHashMismatch if the resolver returns a different link.
+// Since every ancestor must hash to its child's parent hash, the ancestors
+// can't form a cycle.
+func Ancestors(ctx context.Context, r Resolver, s *Segment) ([]*Segment, error) {
+	var ancestors []*Segment
+
+	for prev := s.Link.PrevLinkHash(); len(prev) > 0; prev = s.Link.PrevLinkHash() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		parent, err := r.Resolve(ctx, prev)
+		if err != nil {
+			return nil, errors.Wrap(err, prev.String())
+		}
+
+		if err := checkResolved(parent, prev); err != nil {
+			return nil, err
+		}
+
+		ancestors = append(ancestors, parent)
+		s = parent
+	}
+
+	return ancestors, nil
+}
+
+// Descendants returns the segment's descendants in breadth-first order.
+func Descendants(ctx context.Context, r ReverseResolver, s *Segment) ([]*Segment, error) {
+	var descendants []*Segment
+	err := Walk(ctx, r, s, &WalkOptions{Edges: EdgeChildren}, func(d *Segment, depth int) error {
+		if depth > 0 {
+			descendants = append(descendants, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return descendants, nil
+}
+
+// Referenced returns the segments referenced by the given segment.
+// References that can't be resolved are skipped.
+func Referenced(ctx context.Context, r Resolver, s *Segment) ([]*Segment, error) {
+	var results []*Segment
+	for _, ref := range s.Link.GetMeta().GetRefs() {
+		referenced, err := resolve(ctx, r, ref.LinkHash)
+		if err != nil {
+			return nil, err
+		}
+		if referenced != nil {
+			results = append(results, referenced)
+		}
+	}
+
+	return results, nil
+}
+
+// ReferencedBy returns the segments that reference the given segment.
+// It returns ErrUnrelatedSegment if the resolver returns a segment that
+// doesn't reference it.
+func ReferencedBy(ctx context.Context, r ReverseResolver, s *Segment) ([]*Segment, error) {
+	lh, err := s.Link.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	referencing, err := r.Referencing(ctx, lh)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkReverseResolved(referencing, lh, isReferencing); err != nil {
+		return nil, err
+	}
+
+	return referencing, nil
+}
+
+// childrenOf returns the segments whose parent is the given segment.
+func childrenOf(ctx context.Context, r ReverseResolver, s *Segment) ([]*Segment, error) {
+	lh, err := s.Link.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := r.Children(ctx, lh)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkReverseResolved(children, lh, isChild); err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// DanglingRefs returns the references of the given segment that can't be
+// resolved.
+func DanglingRefs(ctx context.Context, r Resolver, s *Segment) ([]*LinkReference, error) {
+	var dangling []*LinkReference
+	for _, ref := range s.Link.GetMeta().GetRefs() {
+		referenced, err := resolve(ctx, r, ref.LinkHash)
+		if err != nil {
+			return nil, err
+		}
+		if referenced == nil {
+			dangling = append(dangling, ref)
+		}
+	}
+
+	return dangling, nil
+}
@@ -0,0 +1,351 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testGraph is a small link graph spanning two processes:
+//
+//	p1:  root -> child1 -> grandChild
+//	         \-> child2
+//	p2:  audit (refs child1 and a missing link)
+type testGraph struct {
+	root, child1, child2, grandChild, audit *chainscript.Segment
+	missingRef                              *chainscript.LinkReference
+}
+
+func newTestGraph(t *testing.T) *testGraph {
+	g := &testGraph{}
+
+	g.root = chainscripttest.NewLinkBuilder(t).WithProcess("p1").WithStep("root").Segmentify(t)
+	g.child1 = chainscripttest.NewLinkBuilder(t).Branch(t, g.root.Link).WithStep("child1").Segmentify(t)
+	g.child2 = chainscripttest.NewLinkBuilder(t).Branch(t, g.root.Link).WithStep("child2").Segmentify(t)
+	g.grandChild = chainscripttest.NewLinkBuilder(t).Branch(t, g.child1.Link).WithStep("grandChild").Segmentify(t)
+
+	g.missingRef = &chainscript.LinkReference{Process: "p3", LinkHash: chainscripttest.RandomHash()}
+	audit := chainscripttest.NewLinkBuilder(t).WithProcess("p2").WithRef(t, g.child1.Link).Build()
+	audit.Meta.Refs = append(audit.Meta.Refs, g.missingRef)
+
+	var err error
+	g.audit, err = audit.Segmentify()
+	require.NoError(t, err)
+
+	return g
+}
+
+func (g *testGraph) resolver() *chainscript.SegmentsResolver {
+	return chainscript.NewSegmentsResolver(g.root, g.child1, g.child2, g.grandChild, g.audit)
+}
+
+// steps returns the step of each segment to make assertions readable.
+func steps(segments []*chainscript.Segment) []string {
+	var results []string
+	for _, s := range segments {
+		if len(s.Link.Meta.Step) > 0 {
+			results = append(results, s.Link.Meta.Step)
+		} else {
+			results = append(results, s.Link.Meta.Process.Name)
+		}
+	}
+
+	return results
+}
+
+// lyingResolver is a reverse resolver returning the same segments as the
+// children and the referencing segments of every link.
+type lyingResolver struct {
+	*chainscript.SegmentsResolver
+	segments []*chainscript.Segment
+}
+
+func (r *lyingResolver) Children(context.Context, chainscript.LinkHash) ([]*chainscript.Segment, error) {
+	return r.segments, nil
+}
+
+func (r *lyingResolver) Referencing(context.Context, chainscript.LinkHash) ([]*chainscript.Segment, error) {
+	return r.segments, nil
+}
+
+// cycle returns two forged segments claiming to be each other's parent.
+func cycle(t *testing.T) (*chainscript.Segment, *chainscript.Segment) {
+	h1, h2 := chainscripttest.RandomHash(), chainscripttest.RandomHash()
+	s1 := chainscripttest.NewLinkBuilder(t).WithParentHash(h2).Segmentify(t)
+	s1.Meta.LinkHash = h1
+	s2 := chainscripttest.NewLinkBuilder(t).WithParentHash(h1).Segmentify(t)
+	s2.Meta.LinkHash = h2
+
+	return s1, s2
+}
+
+func TestAncestors(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGraph(t)
+
+	t.Run("root", func(t *testing.T) {
+		ancestors, err := chainscript.Ancestors(ctx, g.resolver(), g.root)
+		require.NoError(t, err)
+		assert.Empty(t, ancestors)
+	})
+
+	t.Run("grand child", func(t *testing.T) {
+		ancestors, err := chainscript.Ancestors(ctx, g.resolver(), g.grandChild)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"child1", "root"}, steps(ancestors))
+	})
+
+	t.Run("missing ancestor", func(t *testing.T) {
+		r := chainscript.NewSegmentsResolver(g.child1, g.grandChild)
+		_, err := chainscript.Ancestors(ctx, r, g.grandChild)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrSegmentNotFound.Error())
+	})
+
+	t.Run("resolved link mismatch", func(t *testing.T) {
+		// A segment claiming to be child1 whose parent is itself: without
+		// hash checks, the resolver would make Ancestors loop forever.
+		forged := chainscripttest.NewLinkBuilder(t).WithParentHash(g.child1.LinkHash()).Segmentify(t)
+		forged.Meta.LinkHash = g.child1.LinkHash()
+		r := chainscript.NewSegmentsResolver(forged, g.grandChild)
+
+		_, err := chainscript.Ancestors(ctx, r, g.grandChild)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+
+		err = chainscript.Walk(ctx, r, g.grandChild, &chainscript.WalkOptions{Edges: chainscript.EdgeParent}, func(*chainscript.Segment, int) error { return nil })
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		s1, s2 := cycle(t)
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		_, err := chainscript.Ancestors(ctx, chainscript.NewSegmentsResolver(s1, s2), s1)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+}
+
+func TestDescendants(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGraph(t)
+
+	t.Run("descendants", func(t *testing.T) {
+		descendants, err := chainscript.Descendants(ctx, g.resolver(), g.root)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"child1", "child2", "grandChild"}, steps(descendants))
+	})
+
+	t.Run("forged child", func(t *testing.T) {
+		// A child claiming to be child2 to be visited only once.
+		forged := chainscripttest.NewLinkBuilder(t).Branch(t, g.root.Link).WithStep("forged").Segmentify(t)
+		forged.Meta.LinkHash = g.child2.LinkHash()
+		r := &lyingResolver{SegmentsResolver: g.resolver(), segments: []*chainscript.Segment{forged}}
+
+		_, err := chainscript.Descendants(ctx, r, g.root)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+
+	t.Run("unrelated child", func(t *testing.T) {
+		r := &lyingResolver{SegmentsResolver: g.resolver(), segments: []*chainscript.Segment{g.audit}}
+
+		_, err := chainscript.Descendants(ctx, r, g.root)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUnrelatedSegment.Error())
+	})
+}
+
+func TestReferences(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGraph(t)
+	r := g.resolver()
+
+	referenced, err := chainscript.Referenced(ctx, r, g.audit)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"child1"}, steps(referenced))
+
+	referencing, err := chainscript.ReferencedBy(ctx, r, g.child1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p2"}, steps(referencing))
+
+	dangling, err := chainscript.DanglingRefs(ctx, r, g.audit)
+	require.NoError(t, err)
+	assert.Equal(t, []*chainscript.LinkReference{g.missingRef}, dangling)
+
+	t.Run("unrelated referencing segment", func(t *testing.T) {
+		lying := &lyingResolver{SegmentsResolver: r, segments: []*chainscript.Segment{g.audit}}
+
+		_, err := chainscript.ReferencedBy(ctx, lying, g.root)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUnrelatedSegment.Error())
+
+		err = chainscript.Walk(ctx, lying, g.root, &chainscript.WalkOptions{Edges: chainscript.EdgeReferencedBy}, func(*chainscript.Segment, int) error { return nil })
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUnrelatedSegment.Error())
+	})
+}
+
+func TestWalk(t *testing.T) {
+	ctx := context.Background()
+	g := newTestGraph(t)
+
+	walk := func(r chainscript.Resolver, start *chainscript.Segment, opts *chainscript.WalkOptions) (map[string]int, error) {
+		visited := make(map[string]int)
+		err := chainscript.Walk(ctx, r, start, opts, func(s *chainscript.Segment, depth int) error {
+			visited[steps([]*chainscript.Segment{s})[0]] = depth
+			return nil
+		})
+
+		return visited, err
+	}
+
+	t.Run("cross-process", func(t *testing.T) {
+		visited, err := walk(g.resolver(), g.grandChild, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{
+			"grandChild": 0,
+			"child1":     1,
+			"root":       2,
+			"p2":         2,
+			"child2":     3,
+		}, visited)
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		visited, err := walk(g.resolver(), g.grandChild, &chainscript.WalkOptions{MaxDepth: 1})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"grandChild": 0, "child1": 1}, visited)
+	})
+
+	t.Run("selected edges", func(t *testing.T) {
+		visited, err := walk(g.resolver(), g.audit, &chainscript.WalkOptions{
+			Edges: chainscript.EdgeRefs | chainscript.EdgeParent,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"p2": 0, "child1": 1, "root": 2}, visited)
+	})
+
+	t.Run("skip segment", func(t *testing.T) {
+		var visited []string
+		err := chainscript.Walk(ctx, g.resolver(), g.root, nil, func(s *chainscript.Segment, _ int) error {
+			visited = append(visited, s.Link.Meta.Step)
+			if s.Link.Meta.Step == "child1" {
+				return chainscript.ErrSkipSegment
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"root", "child1", "child2"}, visited)
+	})
+
+	t.Run("reverse resolver required", func(t *testing.T) {
+		r := struct{ chainscript.Resolver }{g.resolver()}
+
+		_, err := walk(r, g.root, nil)
+		assert.EqualError(t, err, chainscript.ErrReverseResolverRequired.Error())
+
+		visited, err := walk(r, g.grandChild, &chainscript.WalkOptions{Edges: chainscript.EdgeParent})
+		require.NoError(t, err)
+		assert.Len(t, visited, 3)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := chainscript.Walk(ctx, g.resolver(), g.root, nil, func(*chainscript.Segment, int) error { return nil })
+		assert.EqualError(t, err, context.Canceled.Error())
+	})
+}
+
+func TestRandomMap(t *testing.T) {
+	ctx := context.Background()
+
+	other := chainscripttest.RandomMap(t, &chainscripttest.MapOptions{Process: "other"})
+	opts := &chainscripttest.MapOptions{
+		Seed:          42,
+		Depth:         4,
+		MaxChildren:   3,
+		MaxRefs:       2,
+		RefTargets:    other.Segments,
+		MaxSignatures: 2,
+		Keys:          [][]byte{chainscripttest.RandomPrivateKey(t), chainscripttest.RandomPrivateKey(t)},
+		MaxEvidences:  2,
+	}
+
+	m := chainscripttest.RandomMap(t, opts)
+	r := chainscript.NewSegmentsResolver(append(other.Segments, m.Segments...)...)
+
+	children := make(map[string]int)
+	for _, s := range m.Segments {
+		assert.NoError(t, s.Validate(ctx))
+		assert.Equal(t, m.Process, s.Link.Meta.Process.Name)
+		assert.Equal(t, m.MapID, s.Link.Meta.MapId)
+
+		dangling, err := chainscript.DanglingRefs(ctx, r, s)
+		require.NoError(t, err)
+		assert.Empty(t, dangling)
+
+		if prev := s.Link.PrevLinkHash(); len(prev) > 0 {
+			children[prev.String()]++
+		}
+	}
+
+	for _, s := range m.Segments {
+		if d := s.Link.Meta.OutDegree; d >= 0 {
+			assert.True(t, children[s.LinkHash().String()] <= int(d), "out degree")
+		}
+	}
+
+	descendants, err := chainscript.Descendants(ctx, m.Resolver(), m.Root())
+	require.NoError(t, err)
+	assert.Len(t, descendants, len(m.Segments)-1)
+
+	maxDepth := 0
+	for _, leaf := range m.Leaves() {
+		ancestors, err := chainscript.Ancestors(ctx, r, leaf)
+		require.NoError(t, err)
+		if len(ancestors) > maxDepth {
+			maxDepth = len(ancestors)
+		}
+	}
+	assert.Equal(t, opts.Depth-1, maxDepth)
+
+	t.Run("reproducible", func(t *testing.T) {
+		m2 := chainscripttest.RandomMap(t, opts)
+		require.Len(t, m2.Segments, len(m.Segments))
+		for i := range m.Segments {
+			chainscripttest.SegmentsEqual(t, m.Segments[i], m2.Segments[i])
+		}
+	})
+
+	t.Run("derived keys", func(t *testing.T) {
+		derived := *opts
+		derived.Keys = nil
+
+		m1 := chainscripttest.RandomMap(t, &derived)
+		m2 := chainscripttest.RandomMap(t, &derived)
+		assert.Equal(t, m1.Keys, m2.Keys)
+		require.Len(t, m2.Segments, len(m1.Segments))
+		for i := range m1.Segments {
+			chainscripttest.SegmentsEqual(t, m1.Segments[i], m2.Segments[i])
+		}
+	})
+}
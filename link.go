@@ -17,6 +17,8 @@ package chainscript
 import (
 	"context"
 	"crypto/sha256"
+	"fmt"
+	"time"
 
 	json "github.com/gibson042/canonicaljson-go"
 	"github.com/golang/protobuf/proto"
@@ -140,14 +142,23 @@ func (l *Link) StructurizeMetadata(metadata interface{}) error {
 // Hash serializes the link and computes a hash of the resulting bytes.
 // The serialization and hashing algorithm used depend on the link version.
 func (l *Link) Hash() (LinkHash, error) {
-	switch l.Version {
-	case LinkVersion1_0_0:
-		b, err := proto.Marshal(l)
-		if err != nil {
-			return nil, err
-		}
+	if l.Version != LinkVersion1_0_0 {
+		return nil, ErrUnknownLinkVersion
+	}
 
-		lh := sha256.Sum256(b)
+	b, err := proto.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashEncodedLink(l.Version, b)
+}
+
+// hashEncodedLink hashes the protobuf-encoded bytes of a link.
+func hashEncodedLink(version string, encoded []byte) (LinkHash, error) {
+	switch version {
+	case LinkVersion1_0_0:
+		lh := sha256.Sum256(encoded)
 		return lh[:], nil
 	default:
 		return nil, ErrUnknownLinkVersion
@@ -189,52 +200,87 @@ func (l *Link) Segmentify() (*Segment, error) {
 	}, nil
 }
 
-// Clone returns a copy of the link.
+// Clone returns a deep copy of the link.
+// The copy has the same protobuf encoding, so it has the same hash.
+// The error is always nil; it is kept for backwards compatibility.
 func (l *Link) Clone() (*Link, error) {
-	var clone Link
-	js, err := json.Marshal(l)
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-
-	if err := json.Unmarshal(js, &clone); err != nil {
-		return nil, errors.WithStack(err)
-	}
-
-	return &clone, nil
+	return proto.Clone(l).(*Link), nil
 }
 
 // Validate checks for errors in a link.
+// It reports every problem found in a ValidationError.
+// If the context contains a validator chain (see WithValidatorChain), it is
+// run once the structural checks pass.
 func (l *Link) Validate(ctx context.Context) error {
+	return l.validate(ctx, newLinkDigest(l), time.Time{})
+}
+
+// validate checks for errors in a link, using the digest to compute its hash
+// and signed bytes.
+// If the context contains a trust store, signature keys must be trusted at
+// the given time (the current time if it is zero).
+func (l *Link) validate(ctx context.Context, d *linkDigest, at time.Time) error {
+	verr := &ValidationError{}
+
 	if len(l.Version) == 0 {
-		return ErrMissingVersion
+		verr.AddError("version", ErrMissingVersion)
+	} else if _, err := d.linkHash(); err != nil {
+		verr.AddError("version", err)
 	}
+
 	if l.Meta == nil || l.Meta.Process == nil || len(l.Meta.Process.Name) == 0 {
-		return ErrMissingProcess
+		verr.AddError("meta.process.name", ErrMissingProcess)
 	}
-	if len(l.Meta.MapId) == 0 {
-		return ErrMissingMapID
+	if l.Meta == nil || len(l.Meta.MapId) == 0 {
+		verr.AddError("meta.mapId", ErrMissingMapID)
 	}
-
-	if _, err := l.Hash(); err != nil {
-		return err
+	if l.Meta != nil {
+		if err := l.compatible(); err != nil {
+			verr.AddError("meta.clientId", err)
+		}
 	}
 
-	for _, ref := range l.Meta.Refs {
-		if len(ref.Process) == 0 {
-			return ErrMissingProcess
+	if l.Meta != nil {
+		for i, ref := range l.Meta.Refs {
+			if len(ref.Process) == 0 {
+				verr.AddError(fmt.Sprintf("meta.refs[%d].process", i), ErrMissingProcess)
+			}
+
+			if len(ref.LinkHash) == 0 {
+				verr.AddError(fmt.Sprintf("meta.refs[%d].linkHash", i), ErrMissingLinkHash)
+			}
 		}
+	}
 
-		if len(ref.LinkHash) == 0 {
-			return ErrMissingLinkHash
+	valid := make([]bool, len(l.Signatures))
+	for i, sig := range l.Signatures {
+		if err := sig.validate(ctx, l, d, at); err != nil {
+			verr.AddError(fmt.Sprintf("signatures[%d]", i), err)
+		} else {
+			valid[i] = true
 		}
 	}
 
-	for _, sig := range l.Signatures {
-		if err := sig.Validate(l); err != nil {
+	l.validateCountersignatures(valid, verr)
+
+	if err := verr.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	if ts := TrustStoreFromContext(ctx); ts != nil {
+		if at.IsZero() {
+			at = now(ctx)
+		}
+
+		checkTrust(ctx, ts, l, at, verr)
+		if err := verr.ErrorOrNil(); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if c := ValidatorChainFromContext(ctx); c != nil {
+		verr.Merge("", c.Validate(ctx, l))
+	}
+
+	return verr.ErrorOrNil()
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 	"github.com/stratumn/go-chainscript"
 	"github.com/stratumn/go-chainscript/chainscripttest"
@@ -192,13 +193,51 @@ func TestLink_TagMap(t *testing.T) {
 }
 
 func TestLink_Clone(t *testing.T) {
-	l := chainscripttest.NewLinkBuilder(t).Build()
+	t.Run("deep copy", func(t *testing.T) {
+		parent := chainscripttest.RandomLink(t)
+		l := chainscripttest.NewLinkBuilder(t).
+			WithRandomData().
+			WithParent(t, parent).
+			WithRef(t, parent).
+			WithSignature(t, "").
+			Build()
+
+		ll, err := l.Clone()
+		require.NoError(t, err)
 
-	ll, err := l.Clone()
-	require.NoError(t, err)
+		assert.True(t, proto.Equal(l, ll))
+		assert.False(t, l == ll)
+
+		ll.Meta.Refs[0].Process = "other"
+		ll.Signatures[0].Signature[0]++
+		ll.Data[0]++
+		assert.False(t, proto.Equal(l, ll))
+	})
+
+	t.Run("preserves hash", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			lb := chainscripttest.NewLinkBuilder(t).WithRandomData()
+			switch i % 4 {
+			case 0:
+				lb = lb.WithSignature(t, "")
+			case 1:
+				lb = lb.WithRef(t, chainscripttest.RandomLink(t)).WithDegree(i)
+			case 2:
+				lb = lb.WithTags().WithPriority(float64(i) / 3)
+			}
+			l := lb.Build()
+
+			ll, err := l.Clone()
+			require.NoError(t, err)
 
-	assert.Equal(t, l, ll)
-	assert.False(t, l == ll)
+			lh, err := l.Hash()
+			require.NoError(t, err)
+			llh, err := ll.Hash()
+			require.NoError(t, err)
+
+			assert.Equal(t, lh, llh, "link %d", i)
+		}
+	})
 }
 
 func TestLink_Segmentify(t *testing.T) {
@@ -246,6 +285,13 @@ func TestLink_Validate(t *testing.T) {
 			return l
 		},
 		chainscript.ErrMissingMapID,
+	}, {
+		"unknown client ID",
+		func(*testing.T) *chainscript.Link {
+			l := chainscripttest.NewLinkBuilder(t).WithClientID("github.com/someguy/someapp").Build()
+			return l
+		},
+		chainscript.ErrUnknownClientID,
 	}, {
 		"invalid ref",
 		func(*testing.T) *chainscript.Link {
@@ -0,0 +1,213 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-crypto/signatures"
+)
+
+const (
+	// LocalTimestampBackend is the backend of the evidences produced by
+	// LocalTimestampProvider.
+	LocalTimestampBackend = "local-timestamp"
+
+	// LocalTimestampVersion is the version of the local timestamp proofs.
+	LocalTimestampVersion = "1.0.0"
+)
+
+// Timestamp errors.
+var (
+	ErrInvalidLinkHashSize = errors.New("timestamped link hash must be 32 bytes")
+)
+
+func init() {
+	RegisterProofDecoder(LocalTimestampBackend, func(e *Evidence) (Proof, error) {
+		var p LocalTimestampProof
+		if err := json.Unmarshal(e.Proof, &p); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return &p, nil
+	})
+}
+
+// LocalTimestampProof is a link hash and a time signed by a local key.
+// It proves that the holder of the key saw the link at that time, so it can
+// only be trusted as much as the key (see TrustStore).
+type LocalTimestampProof struct {
+	Timestamp uint64   `json:"timestamp"`
+	LinkHash  LinkHash `json:"linkHash"`
+	PublicKey []byte   `json:"publicKey"`
+	Signature []byte   `json:"signature"`
+}
+
+// Time returns the signed timestamp (UNIX format).
+func (p *LocalTimestampProof) Time() uint64 {
+	return p.Timestamp
+}
+
+// Verify checks that the proof signs the given link hash.
+// It doesn't check that the public key is trusted: anyone can sign a link
+// hash with any time, so callers should check the key (for example with a
+// TrustStore) before trusting the time (see WithEvidenceTrust).
+func (p *LocalTimestampProof) Verify(linkHash interface{}) bool {
+	signedBytes := localTimestampSignedBytes(p.LinkHash, p.Timestamp)
+	return verifyTimestamp(linkHash, p.LinkHash, signedBytes, p.PublicKey, p.Signature)
+}
+
+// checkTimestampedLinkHash checks that a link hash can be timestamped.
+func checkTimestampedLinkHash(linkHash LinkHash) error {
+	switch len(linkHash) {
+	case 0:
+		return ErrMissingLinkHash
+	case sha256.Size:
+		return nil
+	default:
+		return ErrInvalidLinkHashSize
+	}
+}
+
+// verifyTimestamp checks that the timestamped link hash is the given link
+// hash (a LinkHash or a []byte) and that the signature of the signed bytes
+// is valid.
+func verifyTimestamp(linkHash interface{}, timestamped LinkHash, signedBytes, publicKey, signature []byte) bool {
+	var lh []byte
+	switch v := linkHash.(type) {
+	case LinkHash:
+		lh = v
+	case []byte:
+		lh = v
+	default:
+		return false
+	}
+
+	if checkTimestampedLinkHash(timestamped) != nil || !bytes.Equal(lh, timestamped) {
+		return false
+	}
+
+	sig := signatures.Signature{
+		Message:   signedBytes,
+		PublicKey: publicKey,
+		Signature: signature,
+	}
+
+	return signatures.Verify(&sig) == nil
+}
+
+// timestampDomain returns the prefix of the bytes signed by the proofs of a
+// backend: the backend and the proof version followed by a NUL byte. It
+// prevents a signature from being valid for another backend or version.
+func timestampDomain(backend, version string) []byte {
+	return []byte(backend + "/" + version + "\x00")
+}
+
+// localTimestampSignedBytes returns the domain of local timestamps followed
+// by the link hash and the big-endian timestamp.
+func localTimestampSignedBytes(linkHash LinkHash, timestamp uint64) []byte {
+	b := timestampDomain(LocalTimestampBackend, LocalTimestampVersion)
+	b = append(b, linkHash...)
+	b = append(b, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(b[len(b)-8:], timestamp)
+
+	return b
+}
+
+// LocalTimestampProvider is an evidence provider that signs link hashes with
+// the current time (see WithClock). It works offline and is useful in tests
+// or when the signing key is trusted. Use an Issuer when verifiers need to
+// detect backdated timestamps.
+type LocalTimestampProvider struct {
+	synchronousProvider
+
+	provider   string
+	privateKey []byte
+}
+
+// NewLocalTimestampProvider creates a provider signing with the given key.
+func NewLocalTimestampProvider(provider string, privateKey []byte) *LocalTimestampProvider {
+	return &LocalTimestampProvider{provider: provider, privateKey: privateKey}
+}
+
+// Backend returns LocalTimestampBackend.
+func (p *LocalTimestampProvider) Backend() string {
+	return LocalTimestampBackend
+}
+
+// Provider returns the provider's name.
+func (p *LocalTimestampProvider) Provider() string {
+	return p.provider
+}
+
+// Submit timestamps the link hashes.
+func (p *LocalTimestampProvider) Submit(ctx context.Context, linkHashes []LinkHash) (map[string]*Evidence, error) {
+	timestamp := uint64(now(ctx).Unix())
+	return submitEach(linkHashes, func(lh LinkHash) (*Evidence, error) {
+		return p.timestamp(lh, timestamp)
+	})
+}
+
+func (p *LocalTimestampProvider) timestamp(linkHash LinkHash, timestamp uint64) (*Evidence, error) {
+	if err := checkTimestampedLinkHash(linkHash); err != nil {
+		return nil, err
+	}
+
+	sig, err := signatures.Sign(p.privateKey, localTimestampSignedBytes(linkHash, timestamp))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	proof, err := json.Marshal(&LocalTimestampProof{
+		Timestamp: timestamp,
+		LinkHash:  linkHash,
+		PublicKey: sig.PublicKey,
+		Signature: sig.Signature,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return NewEvidence(LocalTimestampVersion, LocalTimestampBackend, p.provider, proof)
+}
+
+// synchronousProvider implements Poll for evidence providers returning every
+// evidence from Submit.
+type synchronousProvider struct{}
+
+// Poll returns no evidences: they are all returned by Submit.
+func (synchronousProvider) Poll(context.Context, []LinkHash) (map[string]*Evidence, error) {
+	return nil, nil
+}
+
+// submitEach produces an evidence for each link hash, indexed by link hash.
+func submitEach(linkHashes []LinkHash, produce func(LinkHash) (*Evidence, error)) (map[string]*Evidence, error) {
+	evidences := make(map[string]*Evidence, len(linkHashes))
+	for _, lh := range linkHashes {
+		e, err := produce(lh)
+		if err != nil {
+			return nil, err
+		}
+
+		evidences[lh.String()] = e
+	}
+
+	return evidences, nil
+}
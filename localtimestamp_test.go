@@ -0,0 +1,108 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stratumn/go-crypto/signatures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalTimestampProvider(t *testing.T) {
+	signingTime := time.Unix(1500000000, 0)
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return signingTime })
+
+	p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+	assert.Equal(t, chainscript.LocalTimestampBackend, p.Backend())
+	assert.Equal(t, "local", p.Provider())
+
+	lh := chainscripttest.RandomHash()
+	evidences, err := p.Submit(ctx, []chainscript.LinkHash{lh})
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+
+	e := evidences[lh.String()]
+	require.NotNil(t, e)
+	assert.Equal(t, chainscript.LocalTimestampVersion, e.Version)
+	assert.Equal(t, "local", e.Provider)
+
+	proof, err := e.DecodeProof()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(signingTime.Unix()), proof.Time())
+
+	t.Run("verify", func(t *testing.T) {
+		assert.True(t, proof.Verify(lh))
+		assert.True(t, proof.Verify([]byte(lh)))
+		assert.False(t, proof.Verify(chainscripttest.RandomHash()))
+		assert.False(t, proof.Verify(lh.String()))
+	})
+
+	t.Run("tampered time", func(t *testing.T) {
+		tampered := *proof.(*chainscript.LocalTimestampProof)
+		tampered.Timestamp--
+		assert.False(t, tampered.Verify(lh))
+	})
+
+	t.Run("untagged signature", func(t *testing.T) {
+		key := chainscripttest.RandomPrivateKey(t)
+		untagged := make([]byte, len(lh)+8)
+		copy(untagged, lh)
+		binary.BigEndian.PutUint64(untagged[len(lh):], uint64(signingTime.Unix()))
+
+		sig, err := signatures.Sign(key, untagged)
+		require.NoError(t, err)
+
+		forged := &chainscript.LocalTimestampProof{
+			Timestamp: uint64(signingTime.Unix()),
+			LinkHash:  lh,
+			PublicKey: sig.PublicKey,
+			Signature: sig.Signature,
+		}
+		assert.False(t, forged.Verify(lh))
+	})
+
+	t.Run("invalid link hash size", func(t *testing.T) {
+		_, err := p.Submit(ctx, []chainscript.LinkHash{lh[:16]})
+		assert.EqualError(t, err, chainscript.ErrInvalidLinkHashSize.Error())
+
+		truncated := *proof.(*chainscript.LocalTimestampProof)
+		truncated.LinkHash = lh[:16]
+		assert.False(t, truncated.Verify(lh[:16]))
+	})
+
+	t.Run("evidence time", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		evidences, err := p.Submit(ctx, []chainscript.LinkHash{s.LinkHash()})
+		require.NoError(t, err)
+		require.NoError(t, s.AddEvidence(evidences[s.LinkHash().String()]))
+
+		evidenceTime, err := s.EvidenceTime()
+		require.NoError(t, err)
+		assert.Equal(t, signingTime, evidenceTime)
+	})
+
+	t.Run("nothing to poll", func(t *testing.T) {
+		evidences, err := p.Poll(ctx, []chainscript.LinkHash{lh})
+		require.NoError(t, err)
+		assert.Empty(t, evidences)
+	})
+}
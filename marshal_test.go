@@ -94,3 +94,18 @@ func TestMarshal(t *testing.T) {
 		chainscripttest.SegmentsEqual(t, segment, &unmarshalled)
 	})
 }
+
+func TestMarshal_JSONRoundTrip(t *testing.T) {
+	chainscripttest.ForAll(t, 20, func(t *testing.T, g *chainscripttest.Generator) {
+		segment := g.Segment()
+
+		jsonBytes, err := json.Marshal(segment)
+		require.NoError(t, err)
+
+		var unmarshalled chainscript.Segment
+		require.NoError(t, json.Unmarshal(jsonBytes, &unmarshalled))
+		require.NoError(t, unmarshalled.Validate(context.Background()))
+
+		chainscripttest.SegmentsEqual(t, segment, &unmarshalled)
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Merge errors.
+var (
+	ErrMergeLinkHash    = errors.New("cannot merge evidences of segments with different link hashes")
+	ErrEvidenceConflict = errors.New("evidences from the same backend and provider have different proofs")
+)
+
+// EvidenceConflict describes two evidences from the same backend and
+// provider with different proofs.
+type EvidenceConflict struct {
+	Backend  string
+	Provider string
+
+	// Existing is the evidence of the segment being merged into.
+	Existing *Evidence
+	// Incoming is the evidence of the other segment.
+	Incoming *Evidence
+	// Kept is the evidence chosen by the policy.
+	Kept *Evidence
+}
+
+// EvidencePolicy chooses which evidence to keep when two evidences from the
+// same backend and provider have different proofs for the given link hash.
+// It returns either the existing or the incoming evidence, or an error to
+// abort the merge.
+type EvidencePolicy func(linkHash LinkHash, existing, incoming *Evidence) (*Evidence, error)
+
+// KeepExistingEvidence is a policy that keeps the evidence already in the
+// segment.
+func KeepExistingEvidence(_ LinkHash, existing, _ *Evidence) (*Evidence, error) {
+	return existing, nil
+}
+
+// RejectEvidenceConflicts is a policy that aborts merges with conflicts.
+func RejectEvidenceConflicts(_ LinkHash, existing, _ *Evidence) (*Evidence, error) {
+	return nil, errors.Wrapf(ErrEvidenceConflict, "%s/%s", existing.Backend, existing.Provider)
+}
+
+// KeepEarliestEvidence is a policy that keeps the evidence whose proof has
+// the earliest time (see RegisterProofDecoder). Only proofs that verify the
+// link hash are considered, and the existing evidence is kept if neither
+// does.
+// Proofs are verified but their keys aren't checked (see WithEvidenceTrust).
+func KeepEarliestEvidence(linkHash LinkHash, existing, incoming *Evidence) (*Evidence, error) {
+	p1, ok1 := existing.verifiedProof(linkHash)
+	p2, ok2 := incoming.verifiedProof(linkHash)
+
+	switch {
+	case !ok2:
+		return existing, nil
+	case !ok1:
+		return incoming, nil
+	case p2.Time() < p1.Time():
+		return incoming, nil
+	default:
+		return existing, nil
+	}
+}
+
+// KeepLatestVersionEvidence is a policy that keeps the evidence with the
+// highest version (compared numerically, for example 1.10.0 > 1.9.0).
+// The incoming evidence is only kept if its proof verifies the link hash.
+func KeepLatestVersionEvidence(linkHash LinkHash, existing, incoming *Evidence) (*Evidence, error) {
+	if compareVersions(incoming.Version, existing.Version) <= 0 {
+		return existing, nil
+	}
+
+	if _, ok := incoming.verifiedProof(linkHash); !ok {
+		return existing, nil
+	}
+
+	return incoming, nil
+}
+
+// compareVersions compares dot-separated versions. Numeric parts are
+// compared as numbers, other parts as strings.
+func compareVersions(v1, v2 string) int {
+	p1, p2 := strings.Split(v1, "."), strings.Split(v2, ".")
+	for i := 0; i < len(p1) || i < len(p2); i++ {
+		var s1, s2 string
+		if i < len(p1) {
+			s1 = p1[i]
+		}
+		if i < len(p2) {
+			s2 = p2[i]
+		}
+
+		n1, err1 := strconv.ParseUint(s1, 10, 64)
+		n2, err2 := strconv.ParseUint(s2, 10, 64)
+		switch {
+		case err1 == nil && err2 == nil && n1 != n2:
+			if n1 < n2 {
+				return -1
+			}
+			return 1
+		case (err1 != nil || err2 != nil) && s1 != s2:
+			return strings.Compare(s1, s2)
+		}
+	}
+
+	return 0
+}
+
+// MergeEvidences adds the evidences of another copy of the segment.
+// Evidences are identified by their backend and provider. When both
+// segments have different proofs for the same backend and provider, the
+// existing evidence is kept and the conflict is reported.
+func (s *Segment) MergeEvidences(other *Segment) ([]*EvidenceConflict, error) {
+	return s.MergeEvidencesWithPolicy(other, KeepExistingEvidence)
+}
+
+// MergeEvidencesWithPolicy adds the evidences of another copy of the
+// segment, using the policy to resolve conflicts. Conflicts are reported
+// even when the policy resolves them.
+// The segment isn't modified if an error is returned.
+func (s *Segment) MergeEvidencesWithPolicy(other *Segment, policy EvidencePolicy) ([]*EvidenceConflict, error) {
+	linkHash := s.LinkHash()
+	if len(linkHash) == 0 || !bytes.Equal(linkHash, other.LinkHash()) {
+		return nil, ErrMergeLinkHash
+	}
+
+	merged := append([]*Evidence(nil), s.Meta.Evidences...)
+	index := make(map[string]int, len(merged))
+	for i, e := range merged {
+		index[evidenceKey(e)] = i
+	}
+
+	var conflicts []*EvidenceConflict
+	for i, incoming := range other.Meta.Evidences {
+		if err := incoming.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "meta.evidences[%d]", i)
+		}
+
+		key := evidenceKey(incoming)
+		j, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, incoming.Clone())
+			continue
+		}
+
+		existing := merged[j]
+		if existing.Version == incoming.Version && bytes.Equal(existing.Proof, incoming.Proof) {
+			continue
+		}
+
+		kept, err := policy(linkHash, existing, incoming)
+		if err != nil {
+			return nil, err
+		}
+
+		if kept == incoming {
+			merged[j] = incoming.Clone()
+		}
+
+		conflicts = append(conflicts, &EvidenceConflict{
+			Backend:  incoming.Backend,
+			Provider: incoming.Provider,
+			Existing: existing,
+			Incoming: incoming,
+			Kept:     kept,
+		})
+	}
+
+	s.Meta.Evidences = merged
+	return conflicts, nil
+}
+
+// evidenceKey identifies the evidences of a backend and provider.
+func evidenceKey(e *Evidence) string {
+	return e.Backend + "\x00" + e.Provider
+}
@@ -0,0 +1,199 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegment_MergeEvidences(t *testing.T) {
+	// copies returns two copies of the same segment.
+	copies := func(t *testing.T) (*chainscript.Segment, *chainscript.Segment) {
+		s := chainscripttest.RandomSegment(t)
+		return s, s.Clone()
+	}
+
+	evidence := func(backend, provider, version string, proof ...byte) *chainscript.Evidence {
+		return &chainscript.Evidence{Version: version, Backend: backend, Provider: provider, Proof: proof}
+	}
+
+	t.Run("different link hashes", func(t *testing.T) {
+		s1, s2 := chainscripttest.RandomSegment(t), chainscripttest.RandomSegment(t)
+		_, err := s1.MergeEvidences(s2)
+		assert.EqualError(t, err, chainscript.ErrMergeLinkHash.Error())
+
+		_, err = s1.MergeEvidences(&chainscript.Segment{Link: s1.Link})
+		assert.EqualError(t, err, chainscript.ErrMergeLinkHash.Error())
+	})
+
+	t.Run("union", func(t *testing.T) {
+		s1, s2 := copies(t)
+		require.NoError(t, s1.AddEvidence(evidence("btc", "main", "1.0.0", 1)))
+		require.NoError(t, s1.AddEvidence(evidence("eth", "main", "1.0.0", 2)))
+		require.NoError(t, s2.AddEvidence(evidence("eth", "main", "1.0.0", 2)))
+		require.NoError(t, s2.AddEvidence(evidence("btc", "test", "1.0.0", 3)))
+
+		conflicts, err := s1.MergeEvidences(s2)
+		require.NoError(t, err)
+		assert.Empty(t, conflicts)
+
+		require.Len(t, s1.Meta.Evidences, 3)
+		assert.Equal(t, []byte{3}, s1.GetEvidence("btc", "test").Proof)
+		assert.Len(t, s2.Meta.Evidences, 2, "other segment is unchanged")
+
+		// Merged evidences are copies.
+		s2.GetEvidence("btc", "test").Proof[0] = 42
+		assert.Equal(t, []byte{3}, s1.GetEvidence("btc", "test").Proof)
+	})
+
+	t.Run("invalid evidence", func(t *testing.T) {
+		s1, s2 := copies(t)
+		s2.Meta.Evidences = append(s2.Meta.Evidences, evidence("btc", "main", "1.0.0"))
+
+		_, err := s1.MergeEvidences(s2)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrMissingProof.Error())
+		assert.Empty(t, s1.Meta.Evidences)
+	})
+
+	t.Run("conflict keeps existing evidence", func(t *testing.T) {
+		s1, s2 := copies(t)
+		require.NoError(t, s1.AddEvidence(evidence("btc", "main", "1.0.0", 1)))
+		require.NoError(t, s2.AddEvidence(evidence("btc", "main", "1.0.0", 2)))
+
+		conflicts, err := s1.MergeEvidences(s2)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+
+		c := conflicts[0]
+		assert.Equal(t, "btc", c.Backend)
+		assert.Equal(t, "main", c.Provider)
+		assert.Equal(t, []byte{1}, c.Existing.Proof)
+		assert.Equal(t, []byte{2}, c.Incoming.Proof)
+		assert.Equal(t, c.Existing, c.Kept)
+		assert.Equal(t, []byte{1}, s1.GetEvidence("btc", "main").Proof)
+	})
+
+	t.Run("reject conflicts", func(t *testing.T) {
+		s1, s2 := copies(t)
+		require.NoError(t, s1.AddEvidence(evidence("btc", "main", "1.0.0", 1)))
+		require.NoError(t, s2.AddEvidence(evidence("eth", "main", "1.0.0", 3)))
+		require.NoError(t, s2.AddEvidence(evidence("btc", "main", "1.0.0", 2)))
+
+		_, err := s1.MergeEvidencesWithPolicy(s2, chainscript.RejectEvidenceConflicts)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceConflict.Error())
+		assert.Len(t, s1.Meta.Evidences, 1, "segment is unchanged")
+	})
+
+	t.Run("keep earliest proof", func(t *testing.T) {
+		now := time.Now()
+		early, late := timeEvidence(now.Add(-time.Hour)), timeEvidence(now)
+		late.Provider = early.Provider
+
+		s1, s2 := copies(t)
+		require.NoError(t, s1.AddEvidence(late))
+		require.NoError(t, s2.AddEvidence(early))
+
+		conflicts, err := s1.MergeEvidencesWithPolicy(s2, chainscript.KeepEarliestEvidence)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, early, conflicts[0].Kept)
+		assert.Equal(t, early.Proof, s1.Meta.Evidences[0].Proof)
+
+		// Decodable proofs are preferred.
+		invalid := &chainscript.Evidence{Version: "1.0.0", Backend: early.Backend, Provider: early.Provider, Proof: []byte{1}}
+		kept, err := chainscript.KeepEarliestEvidence(s1.LinkHash(), invalid, late)
+		require.NoError(t, err)
+		assert.Equal(t, late, kept)
+	})
+
+	t.Run("forged earlier proof", func(t *testing.T) {
+		now := time.Now()
+		ctx := chainscript.WithClock(context.Background(), func() time.Time { return now })
+		p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+
+		s1, s2 := copies(t)
+		evidences, err := p.Submit(ctx, []chainscript.LinkHash{s1.LinkHash()})
+		require.NoError(t, err)
+		require.NoError(t, s1.AddEvidence(evidences[s1.LinkHash().String()]))
+
+		// An earlier proof of another link hash.
+		other := chainscripttest.RandomHash()
+		earlier := chainscript.WithClock(ctx, func() time.Time { return now.Add(-time.Hour) })
+		forged, err := p.Submit(earlier, []chainscript.LinkHash{other})
+		require.NoError(t, err)
+		require.NoError(t, s2.AddEvidence(forged[other.String()]))
+
+		conflicts, err := s1.MergeEvidencesWithPolicy(s2, chainscript.KeepEarliestEvidence)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, conflicts[0].Existing, conflicts[0].Kept)
+
+		evidenceTime, err := s1.EvidenceTime()
+		require.NoError(t, err)
+		assert.Equal(t, now.Unix(), evidenceTime.Unix())
+	})
+
+	t.Run("keep latest version", func(t *testing.T) {
+		testCases := []struct {
+			existing string
+			incoming string
+			kept     string
+		}{
+			{"1.0.0", "1.0.1", "1.0.1"},
+			{"1.10.0", "1.9.0", "1.10.0"},
+			{"1.0", "1.0.1", "1.0.1"},
+			{"1.0.0-beta", "1.0.0-alpha", "1.0.0-beta"},
+			{"2.0.0", "2.0.0", "2.0.0"},
+		}
+
+		// versioned returns a verifiable evidence with the given version.
+		versioned := func(version string) *chainscript.Evidence {
+			e := timeEvidence(time.Now())
+			e.Version, e.Provider = version, "main"
+			return e
+		}
+
+		lh := chainscripttest.RandomHash()
+		for _, tt := range testCases {
+			e1, e2 := versioned(tt.existing), versioned(tt.incoming)
+			kept, err := chainscript.KeepLatestVersionEvidence(lh, e1, e2)
+			require.NoError(t, err)
+			assert.Equal(t, tt.kept, kept.Version, "%s vs %s", tt.existing, tt.incoming)
+		}
+
+		// Unverified proofs are never kept.
+		unverified := evidence(timeBackend, "main", "1.1.0", 1)
+		kept, err := chainscript.KeepLatestVersionEvidence(lh, versioned("1.0.0"), unverified)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", kept.Version)
+
+		s1, s2 := copies(t)
+		require.NoError(t, s1.AddEvidence(versioned("1.0.0")))
+		require.NoError(t, s2.AddEvidence(versioned("1.1.0")))
+
+		conflicts, err := s1.MergeEvidencesWithPolicy(s2, chainscript.KeepLatestVersionEvidence)
+		require.NoError(t, err)
+		assert.Len(t, conflicts, 1)
+		assert.Equal(t, "1.1.0", s1.GetEvidence(timeBackend, "main").Version)
+	})
+}
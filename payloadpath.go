@@ -0,0 +1,413 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/pkg/errors"
+)
+
+// Payload path errors.
+var (
+	ErrUnknownPayloadPath     = errors.New("unknown payload path preset")
+	ErrUncoveredFields        = errors.New("signature doesn't cover required fields")
+	ErrUnsupportedPayloadPath = errors.New("payload path selects fields depending on their values")
+)
+
+// Names of the payload path presets.
+const (
+	// PayloadPathLink signs the whole link except its signatures.
+	// It is the default payload path.
+	PayloadPathLink = "link"
+	// PayloadPathData signs the link's data.
+	PayloadPathData = "data"
+	// PayloadPathMeta signs the link's metadata.
+	PayloadPathMeta = "meta"
+)
+
+var (
+	payloadPathsMu sync.RWMutex
+	payloadPaths   = map[string]string{
+		PayloadPathLink: "[version,data,meta]",
+		PayloadPathData: "[version,data]",
+		PayloadPathMeta: "[version,meta]",
+	}
+)
+
+// RegisterPayloadPath registers a named payload path, so that applications
+// can share their canonical payload paths instead of hard-coding JMESPath
+// expressions.
+func RegisterPayloadPath(name, payloadPath string) error {
+	if _, err := jmespath.Compile(payloadPath); err != nil {
+		return errors.WithStack(err)
+	}
+
+	payloadPathsMu.Lock()
+	defer payloadPathsMu.Unlock()
+
+	payloadPaths[name] = payloadPath
+	return nil
+}
+
+// PayloadPath returns the payload path registered with the given name.
+func PayloadPath(name string) (string, error) {
+	payloadPathsMu.RLock()
+	defer payloadPathsMu.RUnlock()
+
+	payloadPath, ok := payloadPaths[name]
+	if !ok {
+		return "", errors.Wrap(ErrUnknownPayloadPath, name)
+	}
+
+	return payloadPath, nil
+}
+
+// LinkFields are the fields of a link that a payload path can cover, using
+// their JSON names.
+var LinkFields = []string{
+	"version",
+	"data",
+	"meta.clientId",
+	"meta.prevLinkHash",
+	"meta.priority",
+	"meta.refs",
+	"meta.outDegree",
+	"meta.process.name",
+	"meta.process.state",
+	"meta.mapId",
+	"meta.action",
+	"meta.step",
+	"meta.tags",
+	"meta.data",
+	"signatures",
+}
+
+// maxPayloadPathIndex is the largest index or slice bound supported by
+// CoveredFields. Probe links contain longer lists, so it bounds the number
+// of probes.
+const maxPayloadPathIndex = 16
+
+// linkFieldMutations returns modifications of each field of a probe link
+// whose lists and byte slices have n elements. A field is covered if every
+// modification changes the signed bytes: lists and byte slices are modified
+// element by element (and extended), so a payload path selecting an index,
+// a slice or a sub-field of a field doesn't cover it.
+func linkFieldMutations(n int) map[string][]func(*Link) {
+	ref := func(i int) func(*Link) *LinkReference {
+		return func(l *Link) *LinkReference { return l.Meta.Refs[i] }
+	}
+	sig := func(i int) func(*Link) *Signature {
+		return func(l *Link) *Signature { return l.Signatures[i] }
+	}
+
+	refs := []func(*Link){func(l *Link) { l.Meta.Refs = append(l.Meta.Refs, &LinkReference{Process: "probe"}) }}
+	tags := []func(*Link){func(l *Link) { l.Meta.Tags = append(l.Meta.Tags, "probe") }}
+	sigs := []func(*Link){func(l *Link) { l.Signatures = append(l.Signatures, &Signature{Version: "probe"}) }}
+
+	for i := 0; i < n; i++ {
+		i, ref, sig := i, ref(i), sig(i)
+
+		tags = append(tags, func(l *Link) { l.Meta.Tags[i] = "probe" })
+
+		refs = append(refs, func(l *Link) { ref(l).Process = "probe" })
+		refs = append(refs, bytesMutations(n, func(l *Link) *[]byte { return &ref(l).LinkHash })...)
+
+		sigs = append(sigs,
+			func(l *Link) { sig(l).Version = "probe" },
+			func(l *Link) { sig(l).Type = "probe" },
+			func(l *Link) { sig(l).PayloadPath = "probe" },
+		)
+		sigs = append(sigs, bytesMutations(n, func(l *Link) *[]byte { return &sig(l).PublicKey })...)
+		sigs = append(sigs, bytesMutations(n, func(l *Link) *[]byte { return &sig(l).Signature })...)
+	}
+
+	return map[string][]func(*Link){
+		"version":            {func(l *Link) { l.Version = "probe-version" }},
+		"data":               bytesMutations(n, func(l *Link) *[]byte { return &l.Data }),
+		"meta.clientId":      {func(l *Link) { l.Meta.ClientId = "probe-client" }},
+		"meta.prevLinkHash":  bytesMutations(n, func(l *Link) *[]byte { return &l.Meta.PrevLinkHash }),
+		"meta.priority":      {func(l *Link) { l.Meta.Priority = 42.5 }},
+		"meta.refs":          refs,
+		"meta.outDegree":     {func(l *Link) { l.Meta.OutDegree = 42 }},
+		"meta.process.name":  {func(l *Link) { l.Meta.Process.Name = "probe-process" }},
+		"meta.process.state": {func(l *Link) { l.Meta.Process.State = "probe-state" }},
+		"meta.mapId":         {func(l *Link) { l.Meta.MapId = "probe-map" }},
+		"meta.action":        {func(l *Link) { l.Meta.Action = "probe-action" }},
+		"meta.step":          {func(l *Link) { l.Meta.Step = "probe-step" }},
+		"meta.tags":          tags,
+		"meta.data":          bytesMutations(n, func(l *Link) *[]byte { return &l.Meta.Data }),
+		"signatures":         sigs,
+	}
+}
+
+// bytesMutations returns modifications of each byte of a probe byte slice
+// of length n, and a modification extending it.
+func bytesMutations(n int, field func(*Link) *[]byte) []func(*Link) {
+	mutations := []func(*Link){func(l *Link) {
+		b := field(l)
+		*b = append(*b, 0xff)
+	}}
+
+	for i := 0; i < n; i++ {
+		i := i
+		mutations = append(mutations, func(l *Link) { (*field(l))[i] = 0xff })
+	}
+
+	return mutations
+}
+
+// probeLink returns a link where every field is set and every list and byte
+// slice has n elements.
+func probeLink(n int) *Link {
+	probeBytes := func(offset int) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(offset + i)
+		}
+		return b
+	}
+
+	l := &Link{
+		Version: LinkVersion,
+		Data:    probeBytes(0),
+		Meta: &LinkMeta{
+			ClientId:     ClientID,
+			PrevLinkHash: probeBytes(1),
+			Priority:     1,
+			OutDegree:    1,
+			Process:      &Process{Name: "p", State: "s"},
+			MapId:        "m",
+			Action:       "a",
+			Step:         "s",
+			Data:         probeBytes(2),
+		},
+	}
+
+	for i := 0; i < n; i++ {
+		l.Meta.Refs = append(l.Meta.Refs, &LinkReference{Process: fmt.Sprintf("p%d", i), LinkHash: probeBytes(3 + i)})
+		l.Meta.Tags = append(l.Meta.Tags, fmt.Sprintf("t%d", i))
+		l.Signatures = append(l.Signatures, &Signature{
+			Version:     SignatureVersion,
+			Type:        fmt.Sprintf("type%d", i),
+			PayloadPath: "[version]",
+			PublicKey:   probeBytes(4 + i),
+			Signature:   probeBytes(5 + i),
+		})
+	}
+
+	return l
+}
+
+type coveredFieldsResult struct {
+	fields []string
+	err    error
+}
+
+// The covered fields are cached like compiled payload paths (see
+// maxCompiledPayloadPaths).
+var (
+	coveredFieldsCache sync.Map
+	coveredFieldsCount int32
+)
+
+// CoveredFields returns the link fields (see LinkFields) protected by a
+// signature using the given payload path.
+// A field is covered if the payload path selects the whole field: changing
+// any part of its value changes the signed bytes. A field selected through
+// an index, a slice or a sub-field isn't covered. This is computed by
+// evaluating the payload path on probe links, so payload paths whose
+// selection depends on the link's values (conditionals, filters, functions
+// and literals) and payload paths with indexes or slice bounds greater than
+// 16 are rejected with ErrUnsupportedPayloadPath.
+func CoveredFields(payloadPath string) ([]string, error) {
+	if len(payloadPath) == 0 {
+		payloadPath = "[version,data,meta]"
+	}
+
+	if res, ok := coveredFieldsCache.Load(payloadPath); ok {
+		r := res.(coveredFieldsResult)
+		return append([]string(nil), r.fields...), r.err
+	}
+
+	fields, err := coveredFields(payloadPath)
+
+	if atomic.LoadInt32(&coveredFieldsCount) < maxCompiledPayloadPaths {
+		res := coveredFieldsResult{fields: fields, err: err}
+		if _, loaded := coveredFieldsCache.LoadOrStore(payloadPath, res); !loaded {
+			atomic.AddInt32(&coveredFieldsCount, 1)
+		}
+	}
+
+	return append([]string(nil), fields...), err
+}
+
+func coveredFields(payloadPath string) ([]string, error) {
+	if _, err := compilePayloadPath(payloadPath); err != nil {
+		return nil, err
+	}
+
+	maxIndex, err := checkSelectionPayloadPath(payloadPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Probe lists are longer than any index or slice bound, so that indexes
+	// and slices can't select all their elements.
+	n := maxIndex + 2
+	expected, err := probeLink(n).SignedBytes(SignatureVersion, payloadPath)
+	if err != nil {
+		return nil, err
+	}
+
+	mutations := linkFieldMutations(n)
+
+	var fields []string
+	for _, field := range LinkFields {
+		if coversField(payloadPath, expected, n, mutations[field]) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields, nil
+}
+
+// coversField returns whether each mutation of a probe link changes the
+// bytes signed with the given payload path.
+func coversField(payloadPath string, expected []byte, n int, mutations []func(*Link)) bool {
+	for _, mutate := range mutations {
+		l := probeLink(n)
+		mutate(l)
+
+		signed, err := l.SignedBytes(SignatureVersion, payloadPath)
+		if err == nil && bytes.Equal(signed, expected) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkSelectionPayloadPath checks that the payload path only selects
+// fields (sub-expressions, indexes, slices, projections, pipes and
+// multi-selections), so that the selected fields don't depend on the link's
+// values. It returns the largest index or slice bound of the payload path.
+func checkSelectionPayloadPath(payloadPath string) (int, error) {
+	maxIndex := 0
+	for i := 0; i < len(payloadPath); i++ {
+		switch c := payloadPath[i]; {
+		case c == '"':
+			// Skip quoted identifiers.
+			for i++; i < len(payloadPath) && payloadPath[i] != '"'; i++ {
+				if payloadPath[i] == '\\' {
+					i++
+				}
+			}
+		case c == '|':
+			if i+1 < len(payloadPath) && payloadPath[i+1] == '|' {
+				return 0, errors.Wrap(ErrUnsupportedPayloadPath, payloadPath)
+			}
+		case strings.IndexByte("?(&!=<>`'", c) >= 0:
+			return 0, errors.Wrap(ErrUnsupportedPayloadPath, payloadPath)
+		case isIdentifierByte(c):
+			// Numbers are only indexes and slice bounds outside identifiers.
+			start := i
+			for ; i+1 < len(payloadPath) && isIdentifierByte(payloadPath[i+1]); i++ {
+			}
+
+			index, err := strconv.Atoi(payloadPath[start : i+1])
+			if err != nil {
+				continue
+			}
+			if index > maxPayloadPathIndex {
+				return 0, errors.Wrapf(ErrUnsupportedPayloadPath, "%s: index %d is greater than %d", payloadPath, index, maxPayloadPathIndex)
+			}
+			if index > maxIndex {
+				maxIndex = index
+			}
+		}
+	}
+
+	return maxIndex, nil
+}
+
+// isIdentifierByte returns whether the byte can be part of an unquoted
+// identifier or a number.
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// CoveredFields returns the link fields protected by the signature.
+func (s *Signature) CoveredFields() ([]string, error) {
+	return CoveredFields(s.PayloadPath)
+}
+
+// RequireCoveredFields returns a validator that rejects links containing
+// signatures that don't cover all the given fields.
+// A field also requires its sub-fields (for example "meta" requires every
+// meta field).
+func RequireCoveredFields(fields ...string) Validator {
+	return ValidatorFunc(func(_ context.Context, l *Link) error {
+		verr := &ValidationError{}
+
+		for i, sig := range l.Signatures {
+			covered, err := sig.CoveredFields()
+			if err != nil {
+				verr.AddError(fmt.Sprintf("signatures[%d]", i), err)
+				continue
+			}
+
+			if missing := uncoveredFields(fields, covered); len(missing) > 0 {
+				verr.AddError(
+					fmt.Sprintf("signatures[%d]", i),
+					errors.Wrap(ErrUncoveredFields, strings.Join(missing, ", ")),
+				)
+			}
+		}
+
+		return verr.ErrorOrNil()
+	})
+}
+
+// uncoveredFields returns the required fields (or sub-fields) that aren't
+// covered.
+func uncoveredFields(required, covered []string) []string {
+	isCovered := make(map[string]struct{}, len(covered))
+	for _, f := range covered {
+		isCovered[f] = struct{}{}
+	}
+
+	var missing []string
+	for _, r := range required {
+		for _, f := range LinkFields {
+			if f != r && !strings.HasPrefix(f, r+".") {
+				continue
+			}
+
+			if _, ok := isCovered[f]; !ok {
+				missing = append(missing, f)
+			}
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,229 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadPath(t *testing.T) {
+	p, err := chainscript.PayloadPath(chainscript.PayloadPathLink)
+	require.NoError(t, err)
+	assert.Equal(t, "[version,data,meta]", p)
+
+	_, err = chainscript.PayloadPath("unknown")
+	assert.EqualError(t, errors.Cause(err), chainscript.ErrUnknownPayloadPath.Error())
+
+	err = chainscript.RegisterPayloadPath("invalid", "[version,")
+	assert.Error(t, err)
+
+	require.NoError(t, chainscript.RegisterPayloadPath("map", "[version,meta.mapId,meta.prevLinkHash]"))
+	p, err = chainscript.PayloadPath("map")
+	require.NoError(t, err)
+	assert.Equal(t, "[version,meta.mapId,meta.prevLinkHash]", p)
+}
+
+func TestCoveredFields(t *testing.T) {
+	metaFields := []string{
+		"meta.clientId",
+		"meta.prevLinkHash",
+		"meta.priority",
+		"meta.refs",
+		"meta.outDegree",
+		"meta.process.name",
+		"meta.process.state",
+		"meta.mapId",
+		"meta.action",
+		"meta.step",
+		"meta.tags",
+		"meta.data",
+	}
+
+	testCases := []struct {
+		name        string
+		payloadPath string
+		covered     []string
+	}{{
+		"default",
+		"",
+		append([]string{"version", "data"}, metaFields...),
+	}, {
+		"link",
+		"[version,data,meta]",
+		append([]string{"version", "data"}, metaFields...),
+	}, {
+		"link and signatures",
+		"[version,data,meta,signatures]",
+		append(append([]string{"version", "data"}, metaFields...), "signatures"),
+	}, {
+		"data only",
+		"data",
+		[]string{"data"},
+	}, {
+		"nested fields",
+		"[meta.process.name,meta.prevLinkHash]",
+		[]string{"meta.prevLinkHash", "meta.process.name"},
+	}, {
+		"multi-select hash",
+		"{d: data, p: meta.process}",
+		[]string{"data", "meta.process.name", "meta.process.state"},
+	}, {
+		"countersignature",
+		chainscript.CountersignPayloadPath(0),
+		append([]string{"version", "data"}, metaFields...),
+	}, {
+		"slice",
+		"[version,data,meta.tags[1:]]",
+		[]string{"version", "data"},
+	}, {
+		"bounded slice",
+		"[version,meta.tags[0:16],meta.prevLinkHash[:16]]",
+		[]string{"version"},
+	}, {
+		"slice step",
+		"[version,meta.tags[::2]]",
+		[]string{"version"},
+	}, {
+		"negative indexes",
+		"[version,data,meta.tags[-1],meta.refs[-1]]",
+		[]string{"version", "data"},
+	}, {
+		"index",
+		"[version,meta.prevLinkHash[0],signatures[1]]",
+		[]string{"version"},
+	}, {
+		"sub-fields",
+		"[version,meta.refs[*].process,signatures[*].signature]",
+		[]string{"version"},
+	}, {
+		"quoted identifier",
+		`[version,"data"]`,
+		[]string{"version", "data"},
+	}, {
+		"unknown field",
+		"[version,foo]",
+		[]string{"version"},
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			covered, err := chainscript.CoveredFields(tt.payloadPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.covered, covered)
+		})
+	}
+
+	t.Run("invalid payload path", func(t *testing.T) {
+		_, err := chainscript.CoveredFields("[version,")
+		assert.Error(t, err)
+	})
+
+	unsupported := []string{
+		"meta.process.name=='p' && [version,data,meta] || [version]",
+		"meta.refs[?process=='p']",
+		"[version,length(data)]",
+		"[version,`\"data\"`]",
+		"[version,'data']",
+		"!meta.action && data",
+		"[version,meta.tags[17]]",
+		"[version,meta.tags[:-20]]",
+	}
+
+	for _, payloadPath := range unsupported {
+		t.Run("unsupported/"+payloadPath, func(t *testing.T) {
+			_, err := chainscript.CoveredFields(payloadPath)
+			assert.EqualError(t, errors.Cause(err), chainscript.ErrUnsupportedPayloadPath.Error())
+		})
+	}
+}
+
+func TestRequireCoveredFields(t *testing.T) {
+	ctx := chainscript.WithValidatorChain(
+		context.Background(),
+		chainscript.NewValidatorChain(chainscript.RequireCoveredFields("data", "meta.prevLinkHash")),
+	)
+
+	t.Run("covered", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").Build()
+		assert.NoError(t, l.Validate(ctx))
+
+		covered, err := l.Signatures[0].CoveredFields()
+		require.NoError(t, err)
+		assert.Contains(t, covered, "meta.prevLinkHash")
+	})
+
+	t.Run("uncovered", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).
+			WithSignature(t, "").
+			WithSignature(t, "[version,data]").
+			Build()
+
+		err := l.Validate(ctx)
+		assert.Equal(t, map[string]string{
+			"signatures[1]": chainscript.CodeUncoveredFields,
+		}, problemFields(t, err))
+		assert.Contains(t, err.Error(), "meta.prevLinkHash")
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		ctx := chainscript.WithValidatorChain(
+			context.Background(),
+			chainscript.NewValidatorChain(chainscript.RequireCoveredFields("meta.tags")),
+		)
+
+		l := chainscripttest.NewLinkBuilder(t).
+			WithTags("signed", "unsigned").
+			WithSignature(t, "[version,data,meta.tags[1:]]").
+			Build()
+
+		err := l.Validate(ctx)
+		assert.Equal(t, map[string]string{
+			"signatures[0]": chainscript.CodeUncoveredFields,
+		}, problemFields(t, err))
+	})
+
+	t.Run("data-dependent payload path", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).
+			WithProcess("p").
+			WithSignature(t, "meta.process.name=='p' && [version,data,meta] || [version]").
+			Build()
+
+		err := l.Validate(ctx)
+		assert.Equal(t, map[string]string{
+			"signatures[0]": chainscript.CodeUnsupportedPayloadPath,
+		}, problemFields(t, err))
+	})
+
+	t.Run("parent field", func(t *testing.T) {
+		ctx := chainscript.WithValidatorChain(
+			context.Background(),
+			chainscript.NewValidatorChain(chainscript.RequireCoveredFields("meta")),
+		)
+
+		l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "[version,meta.process]").Build()
+		err := l.Validate(ctx)
+		assert.Equal(t, map[string]string{
+			"signatures[0]": chainscript.CodeUncoveredFields,
+		}, problemFields(t, err))
+		assert.NotContains(t, err.Error(), "meta.process.name")
+	})
+}
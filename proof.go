@@ -14,6 +14,20 @@
 
 package chainscript
 
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Proof errors.
+var (
+	ErrUnknownProofBackend = errors.New("no proof decoder registered for the evidence backend")
+	ErrMissingEvidenceTime = errors.New("segment doesn't have an evidence with a verified proof")
+)
+
 // Proof is the generic interface an evidence's proof should implement.
 type Proof interface {
 	// Time returns the timestamp (UNIX format) of the proof
@@ -25,3 +39,132 @@ type Proof interface {
 	// contained in the merkle path.
 	Verify(interface{}) bool
 }
+
+// ProofDecoder decodes the proof of an evidence.
+type ProofDecoder func(e *Evidence) (Proof, error)
+
+var (
+	proofDecodersMu sync.RWMutex
+	proofDecoders   = make(map[string]ProofDecoder)
+)
+
+// RegisterProofDecoder registers the decoder used for the proofs of the given
+// evidence backend. Packages implementing a backend typically call it in
+// their init function.
+func RegisterProofDecoder(backend string, decoder ProofDecoder) {
+	proofDecodersMu.Lock()
+	defer proofDecodersMu.Unlock()
+
+	proofDecoders[backend] = decoder
+}
+
+// DecodeProof decodes the evidence's proof with the decoder registered for
+// its backend.
+func (e *Evidence) DecodeProof() (Proof, error) {
+	proofDecodersMu.RLock()
+	decoder, ok := proofDecoders[e.Backend]
+	proofDecodersMu.RUnlock()
+
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownProofBackend, e.Backend)
+	}
+
+	return decoder(e)
+}
+
+// verifiedProof decodes the evidence's proof and checks that it proves the
+// given link hash.
+func (e *Evidence) verifiedProof(linkHash LinkHash) (Proof, bool) {
+	proof, err := e.DecodeProof()
+	if err != nil || !proof.Verify(linkHash) {
+		return nil, false
+	}
+
+	return proof, true
+}
+
+// EvidenceTrust decides whether a verified evidence can be trusted to date a
+// link (see WithEvidenceTrust).
+type EvidenceTrust func(ctx context.Context, e *Evidence, proof Proof) bool
+
+// TrustEvidenceBackends trusts the verified proofs of the given backends.
+// It should only be used for backends whose proofs can't be produced by an
+// attacker (for example anchors in a public blockchain).
+func TrustEvidenceBackends(backends ...string) EvidenceTrust {
+	trusted := make(map[string]struct{}, len(backends))
+	for _, b := range backends {
+		trusted[b] = struct{}{}
+	}
+
+	return func(_ context.Context, e *Evidence, _ Proof) bool {
+		_, ok := trusted[e.Backend]
+		return ok
+	}
+}
+
+// WithEvidenceTrust returns a context in which segments are validated at the
+// time of their oldest trusted evidence: signature keys (see WithTrustStore)
+// and certificates are checked at that time.
+// Without evidence trust, they are checked at the current time (see
+// WithClock), so that a forged evidence can't choose the validation time.
+func WithEvidenceTrust(ctx context.Context, trust EvidenceTrust) context.Context {
+	return context.WithValue(ctx, evidenceTrustKey, trust)
+}
+
+// EvidenceTrustFromContext returns the evidence trust stored in the context,
+// or nil if there is none.
+func EvidenceTrustFromContext(ctx context.Context) EvidenceTrust {
+	trust, _ := ctx.Value(evidenceTrustKey).(EvidenceTrust)
+	return trust
+}
+
+// trustedEvidenceTime returns the time of the segment's oldest trusted
+// evidence for the given link hash, or the zero time.
+func (s *Segment) trustedEvidenceTime(ctx context.Context, linkHash LinkHash) time.Time {
+	trust := EvidenceTrustFromContext(ctx)
+	if trust == nil {
+		return time.Time{}
+	}
+
+	at, _ := s.evidenceTime(linkHash, func(e *Evidence, proof Proof) bool {
+		return trust(ctx, e, proof)
+	})
+
+	return at
+}
+
+// EvidenceTime returns the time of the segment's oldest evidence.
+// Evidences that can't be decoded or whose proof doesn't verify the
+// segment's link hash are ignored.
+// The proofs aren't necessarily trusted: anyone can add an evidence to a
+// segment (see WithEvidenceTrust).
+func (s *Segment) EvidenceTime() (time.Time, error) {
+	return s.evidenceTime(s.LinkHash(), nil)
+}
+
+// evidenceTime returns the time of the oldest evidence verifying the link
+// hash and accepted by the given function (if any).
+func (s *Segment) evidenceTime(linkHash LinkHash, accept func(*Evidence, Proof) bool) (time.Time, error) {
+	var oldest time.Time
+	for _, e := range s.GetMeta().GetEvidences() {
+		proof, ok := e.verifiedProof(linkHash)
+		if !ok {
+			continue
+		}
+
+		if accept != nil && !accept(e, proof) {
+			continue
+		}
+
+		t := time.Unix(int64(proof.Time()), 0)
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+	}
+
+	if oldest.IsZero() {
+		return oldest, ErrMissingEvidenceTime
+	}
+
+	return oldest, nil
+}
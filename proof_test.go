@@ -0,0 +1,114 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const timeBackend = "test-time"
+
+// timeProof is a proof that only contains a timestamp.
+type timeProof uint64
+
+func (p timeProof) Time() uint64 { return uint64(p) }
+
+func (p timeProof) Verify(interface{}) bool { return true }
+
+func init() {
+	chainscript.RegisterProofDecoder(timeBackend, func(e *chainscript.Evidence) (chainscript.Proof, error) {
+		if len(e.Proof) != 8 {
+			return nil, errors.New("invalid proof")
+		}
+
+		return timeProof(binary.BigEndian.Uint64(e.Proof)), nil
+	})
+}
+
+// withTimeEvidenceTrust returns a context trusting the test evidences to date
+// links.
+func withTimeEvidenceTrust(ctx context.Context) context.Context {
+	return chainscript.WithEvidenceTrust(ctx, chainscript.TrustEvidenceBackends(timeBackend))
+}
+
+// timeEvidence creates an evidence that can be decoded by the test decoder.
+func timeEvidence(t time.Time) *chainscript.Evidence {
+	proof := make([]byte, 8)
+	binary.BigEndian.PutUint64(proof, uint64(t.Unix()))
+
+	return &chainscript.Evidence{
+		Version:  "1.0.0",
+		Backend:  timeBackend,
+		Provider: chainscripttest.RandomString(8),
+		Proof:    proof,
+	}
+}
+
+func TestEvidence_DecodeProof(t *testing.T) {
+	t.Run("registered backend", func(t *testing.T) {
+		now := time.Now()
+		proof, err := timeEvidence(now).DecodeProof()
+		require.NoError(t, err)
+		assert.Equal(t, uint64(now.Unix()), proof.Time())
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		_, err := chainscripttest.RandomEvidence(t).DecodeProof()
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUnknownProofBackend.Error())
+	})
+}
+
+func TestSegment_EvidenceTime(t *testing.T) {
+	t.Run("oldest evidence", func(t *testing.T) {
+		now := time.Unix(time.Now().Unix(), 0)
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, s.AddEvidence(chainscripttest.RandomEvidence(t)))
+		require.NoError(t, s.AddEvidence(timeEvidence(now)))
+		require.NoError(t, s.AddEvidence(timeEvidence(now.Add(-time.Hour))))
+
+		et, err := s.EvidenceTime()
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-time.Hour), et)
+	})
+
+	t.Run("unverified proof", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		p := chainscript.NewLocalTimestampProvider("local", chainscripttest.RandomPrivateKey(t))
+		other := chainscripttest.RandomHash()
+		evidences, err := p.Submit(context.Background(), []chainscript.LinkHash{other})
+		require.NoError(t, err)
+		require.NoError(t, s.AddEvidence(evidences[other.String()]))
+
+		_, err = s.EvidenceTime()
+		assert.EqualError(t, err, chainscript.ErrMissingEvidenceTime.Error())
+	})
+
+	t.Run("no decodable evidence", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, s.AddEvidence(chainscripttest.RandomEvidence(t)))
+
+		_, err := s.EvidenceTime()
+		assert.EqualError(t, err, chainscript.ErrMissingEvidenceTime.Error())
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Resolver errors.
+var (
+	ErrSegmentNotFound = errors.New("segment not found")
+)
+
+// Resolver finds segments from their link hash.
+// Segments can be in any process. The graph helpers (Walk, Ancestors...)
+// check that resolved links hash to the requested link hash and to their
+// segment's link hash.
+type Resolver interface {
+	// Resolve returns the segment containing the given link.
+	// It returns ErrSegmentNotFound if the segment is unknown.
+	Resolve(ctx context.Context, linkHash LinkHash) (*Segment, error)
+}
+
+// ReverseResolver also finds the segments that point to a given link.
+// It is needed to traverse the link graph from parents to children.
+// The graph helpers check that the segments it returns point to the given
+// link.
+type ReverseResolver interface {
+	Resolver
+
+	// Children returns the segments whose parent is the given link.
+	Children(ctx context.Context, linkHash LinkHash) ([]*Segment, error)
+
+	// Referencing returns the segments that reference the given link.
+	Referencing(ctx context.Context, linkHash LinkHash) ([]*Segment, error)
+}
+
+// SegmentsResolver resolves links from an in-memory collection of segments.
+// It is safe for concurrent use.
+type SegmentsResolver struct {
+	mu          sync.RWMutex
+	segments    map[string]*Segment
+	children    map[string][]*Segment
+	referencing map[string][]*Segment
+}
+
+// NewSegmentsResolver creates a resolver for the given segments.
+func NewSegmentsResolver(segments ...*Segment) *SegmentsResolver {
+	r := &SegmentsResolver{
+		segments:    make(map[string]*Segment),
+		children:    make(map[string][]*Segment),
+		referencing: make(map[string][]*Segment),
+	}
+
+	r.Add(segments...)
+	return r
+}
+
+// Add segments to the resolver.
+// Segments that were already added are ignored.
+func (r *SegmentsResolver) Add(segments ...*Segment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range segments {
+		lh := s.LinkHash().String()
+		if _, ok := r.segments[lh]; ok {
+			continue
+		}
+
+		r.segments[lh] = s
+
+		if prev := s.Link.PrevLinkHash(); len(prev) > 0 {
+			r.children[prev.String()] = append(r.children[prev.String()], s)
+		}
+
+		for _, ref := range s.Link.GetMeta().GetRefs() {
+			refHash := LinkHash(ref.LinkHash).String()
+			r.referencing[refHash] = append(r.referencing[refHash], s)
+		}
+	}
+}
+
+// Resolve returns the segment containing the given link.
+func (r *SegmentsResolver) Resolve(_ context.Context, linkHash LinkHash) (*Segment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.segments[linkHash.String()]
+	if !ok {
+		return nil, ErrSegmentNotFound
+	}
+
+	return s, nil
+}
+
+// Children returns the segments whose parent is the given link.
+func (r *SegmentsResolver) Children(_ context.Context, linkHash LinkHash) ([]*Segment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*Segment(nil), r.children[linkHash.String()]...), nil
+}
+
+// Referencing returns the segments that reference the given link.
+func (r *SegmentsResolver) Referencing(_ context.Context, linkHash LinkHash) ([]*Segment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*Segment(nil), r.referencing[linkHash.String()]...), nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentsResolver(t *testing.T) {
+	ctx := context.Background()
+
+	parent := chainscripttest.RandomSegment(t)
+	child := chainscripttest.NewLinkBuilder(t).Branch(t, parent.Link).Segmentify(t)
+	referencing := chainscripttest.NewLinkBuilder(t).WithRef(t, parent.Link).Segmentify(t)
+
+	r := chainscript.NewSegmentsResolver(parent, child)
+	r.Add(referencing, child)
+
+	t.Run("resolve", func(t *testing.T) {
+		s, err := r.Resolve(ctx, parent.LinkHash())
+		require.NoError(t, err)
+		assert.Equal(t, parent, s)
+
+		_, err = r.Resolve(ctx, chainscripttest.RandomHash())
+		assert.EqualError(t, err, chainscript.ErrSegmentNotFound.Error())
+	})
+
+	t.Run("children", func(t *testing.T) {
+		children, err := r.Children(ctx, parent.LinkHash())
+		require.NoError(t, err)
+		assert.Equal(t, []*chainscript.Segment{child}, children)
+
+		children, err = r.Children(ctx, child.LinkHash())
+		require.NoError(t, err)
+		assert.Empty(t, children)
+	})
+
+	t.Run("referencing", func(t *testing.T) {
+		segments, err := r.Referencing(ctx, parent.LinkHash())
+		require.NoError(t, err)
+		assert.Equal(t, []*chainscript.Segment{referencing}, segments)
+	})
+}
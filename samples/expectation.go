@@ -0,0 +1,202 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+)
+
+// Expectation file errors.
+var (
+	ErrMissingExpectations = errors.New("sample file doesn't have an expectation file")
+	ErrUnexpectedContent   = errors.New("segment doesn't match the expectation")
+)
+
+// SegmentExpectation is a declarative expectation, usually loaded from a
+// JSON file (see LoadExpectations).
+//
+// Segment is a subset of the JSON representation of the segment that must
+// match the decoded segment: objects only need to contain the expected keys,
+// arrays must have the same length. Bytes are base64-encoded, and the link's
+// data and metadata are replaced with the JSON values they contain.
+type SegmentExpectation struct {
+	Segment interface{} `json:"segment"`
+	Error   string      `json:"errorCode,omitempty"`
+}
+
+// ErrorCode returns the code of the expected validation error.
+func (e *SegmentExpectation) ErrorCode() string {
+	return e.Error
+}
+
+// Check compares the segment with the expected subset.
+func (e *SegmentExpectation) Check(s *chainscript.Segment) error {
+	actual, err := segmentJSON(s)
+	if err != nil {
+		return err
+	}
+
+	if path, ok := match(e.Segment, actual, "segment"); !ok {
+		return errors.Wrap(ErrUnexpectedContent, path)
+	}
+
+	return nil
+}
+
+// segmentJSON returns the JSON representation of the segment, with the
+// link's data and metadata decoded.
+func segmentJSON(s *chainscript.Segment) (interface{}, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var segment map[string]interface{}
+	if err := json.Unmarshal(b, &segment); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	link, _ := segment["link"].(map[string]interface{})
+	if link == nil {
+		return segment, nil
+	}
+
+	if len(s.Link.Data) > 0 {
+		var data interface{}
+		if err := s.Link.StructurizeData(&data); err != nil {
+			return nil, err
+		}
+		link["data"] = data
+	}
+
+	if meta, _ := link["meta"].(map[string]interface{}); meta != nil && len(s.Link.Meta.Data) > 0 {
+		var metadata interface{}
+		if err := s.Link.StructurizeMetadata(&metadata); err != nil {
+			return nil, err
+		}
+		meta["data"] = metadata
+	}
+
+	return segment, nil
+}
+
+// match checks that the expected value is a subset of the actual value.
+// It returns the path of the first difference.
+func match(expected, actual interface{}, path string) (string, bool) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return path, false
+		}
+
+		keys := make([]string, 0, len(e))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if p, ok := match(e[k], a[k], path+"."+k); !ok {
+				return p, false
+			}
+		}
+
+		return "", true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return path, false
+		}
+
+		for i := range e {
+			if p, ok := match(e[i], a[i], fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return p, false
+			}
+		}
+
+		return "", true
+	default:
+		return path, reflect.DeepEqual(expected, actual)
+	}
+}
+
+// LoadExpectations loads declarative expectations from a JSON file mapping
+// sample IDs to SegmentExpectation objects.
+func LoadExpectations(path string) (map[string]Expectation, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var expectations map[string]*SegmentExpectation
+	if err := json.Unmarshal(b, &expectations); err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	res := make(map[string]Expectation, len(expectations))
+	for id, e := range expectations {
+		res[id] = e
+	}
+
+	return res, nil
+}
+
+// RunVersions validates every sample file of the samples directory (one
+// file per ChainScript version, for example 1.0.0.json) against the
+// expectation file with the same name in the expectations directory.
+// Results are indexed by version.
+func RunVersions(ctx context.Context, samplesDir, expectationsDir string) (map[string][]*Result, error) {
+	paths, err := filepath.Glob(filepath.Join(samplesDir, "*.json"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	results := make(map[string][]*Result, len(paths))
+	for _, path := range paths {
+		name := filepath.Base(path)
+		version := strings.TrimSuffix(name, ".json")
+
+		expectationsPath := filepath.Join(expectationsDir, name)
+		if _, err := os.Stat(expectationsPath); err != nil {
+			return nil, errors.Wrap(ErrMissingExpectations, version)
+		}
+
+		expectations, err := LoadExpectations(expectationsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		samples, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+
+		results[version] = Run(ctx, samples, expectations)
+	}
+
+	return results, nil
+}
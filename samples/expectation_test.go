@@ -0,0 +1,154 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stratumn/go-chainscript/samples"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentExpectation(t *testing.T) {
+	s := chainscripttest.NewLinkBuilder(t).
+		WithProcess("p").
+		WithMapID("m").
+		WithData(t, map[string]interface{}{"name": "batman", "age": 42}).
+		WithMetadata(t, "bruce wayne").
+		WithTags("tag1", "tag2").
+		Segmentify(t)
+
+	expectation := func(t *testing.T, j string) *samples.SegmentExpectation {
+		var e samples.SegmentExpectation
+		require.NoError(t, json.Unmarshal([]byte(j), &e))
+		return &e
+	}
+
+	t.Run("matching subset", func(t *testing.T) {
+		e := expectation(t, `{"segment": {"link": {
+			"data": {"name": "batman"},
+			"meta": {"process": {"name": "p"}, "data": "bruce wayne", "tags": ["tag1", "tag2"]}
+		}}}`)
+		assert.NoError(t, e.Check(s))
+	})
+
+	testCases := []struct {
+		name string
+		json string
+		path string
+	}{{
+		"different value",
+		`{"segment": {"link": {"meta": {"mapId": "n"}}}}`,
+		"segment.link.meta.mapId",
+	}, {
+		"different data",
+		`{"segment": {"link": {"data": {"age": 41}}}}`,
+		"segment.link.data.age",
+	}, {
+		"missing key",
+		`{"segment": {"link": {"meta": {"step": "setup"}}}}`,
+		"segment.link.meta.step",
+	}, {
+		"different array length",
+		`{"segment": {"link": {"meta": {"tags": ["tag1"]}}}}`,
+		"segment.link.meta.tags",
+	}, {
+		"different array item",
+		`{"segment": {"link": {"meta": {"tags": ["tag1", "tag3"]}}}}`,
+		"segment.link.meta.tags[1]",
+	}, {
+		"object instead of value",
+		`{"segment": {"link": {"version": {"major": 1}}}}`,
+		"segment.link.version",
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := expectation(t, tt.json).Check(s)
+			assert.EqualError(t, err, tt.path+": "+samples.ErrUnexpectedContent.Error())
+		})
+	}
+}
+
+func TestRunVersions(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "samples")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	samplesDir := filepath.Join(dir, "samples")
+	expectationsDir := filepath.Join(dir, "expectations")
+	require.NoError(t, os.Mkdir(samplesDir, 0755))
+	require.NoError(t, os.Mkdir(expectationsDir, 0755))
+
+	write := func(t *testing.T, path string, v interface{}) {
+		b, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path, b, 0644))
+	}
+
+	s := chainscripttest.NewLinkBuilder(t).WithProcess("p").Segmentify(t)
+	write(t, filepath.Join(samplesDir, "1.0.0.json"), []*samples.Sample{newSample(t, "s", s)})
+
+	t.Run("missing expectations", func(t *testing.T) {
+		_, err := samples.RunVersions(ctx, samplesDir, expectationsDir)
+		assert.EqualError(t, errors.Cause(err), samples.ErrMissingExpectations.Error())
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		write(t, filepath.Join(expectationsDir, "1.0.0.json"), map[string]interface{}{
+			"s": map[string]interface{}{
+				"segment": map[string]interface{}{
+					"link": map[string]interface{}{
+						"meta": map[string]interface{}{"process": map[string]interface{}{"name": "p"}},
+					},
+				},
+			},
+		})
+
+		results, err := samples.RunVersions(ctx, samplesDir, expectationsDir)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]*samples.Result{
+			"1.0.0": {{ID: "s"}},
+		}, results)
+	})
+
+	t.Run("error code", func(t *testing.T) {
+		tampered := chainscripttest.NewLinkBuilder(t).WithProcess("p").Segmentify(t)
+		tampered.Meta.LinkHash = chainscripttest.RandomHash()
+
+		sample := newSample(t, "s", tampered)
+		sample.ErrorCode = chainscript.CodeLinkHashMismatch
+		write(t, filepath.Join(samplesDir, "1.0.0.json"), []*samples.Sample{sample})
+		write(t, filepath.Join(expectationsDir, "1.0.0.json"), map[string]interface{}{
+			"s": map[string]interface{}{"errorCode": chainscript.CodeLinkHashMismatch},
+		})
+
+		results, err := samples.RunVersions(ctx, samplesDir, expectationsDir)
+		require.NoError(t, err)
+		require.Len(t, results["1.0.0"], 1)
+		assert.NoError(t, results["1.0.0"][0].Err)
+	})
+}
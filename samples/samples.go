@@ -0,0 +1,184 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package samples validates serialized segment samples, such as the
+// regression samples of https://github.com/stratumn/chainscript/tree/master/samples
+// and the compatibility test data generated by other implementations.
+//
+// A sample file is a JSON array of samples:
+//
+//	[
+//	  { "id": "simple-segment", "data": "<base64-encoded segment>" },
+//	  { "id": "invalid-link-hash", "data": "...", "errorCode": "link_hash_mismatch" }
+//	]
+//
+// Each sample is checked against an Expectation with the same ID.
+package samples
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+)
+
+// Errors returned by the runner.
+var (
+	ErrUnknownSample   = errors.New("sample doesn't have an expectation")
+	ErrMissingSample   = errors.New("expected sample is missing")
+	ErrErrorCode       = errors.New("sample and expectation have different error codes")
+	ErrSegmentAccepted = errors.New("segment should be rejected")
+	ErrSegmentRejected = errors.New("segment was rejected with another error code")
+)
+
+// Sample is a serialized segment.
+// Samples with an error code must be rejected with that code.
+type Sample struct {
+	ID        string `json:"id"`
+	Data      string `json:"data"`
+	ErrorCode string `json:"errorCode,omitempty"`
+}
+
+// Load loads samples from a JSON file.
+func Load(path string) ([]*Sample, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var samples []*Sample
+	if err := json.Unmarshal(b, &samples); err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	return samples, nil
+}
+
+// Decode deserializes the sample's segment.
+func (s *Sample) Decode() (*chainscript.Segment, error) {
+	b, err := base64.StdEncoding.DecodeString(s.Data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return chainscript.UnmarshalSegment(b)
+}
+
+// Expectation describes what a sample must contain.
+type Expectation interface {
+	// ErrorCode is the code of the validation error expected for the
+	// segment, or an empty string if the segment is valid.
+	ErrorCode() string
+	// Check verifies the content of a valid segment.
+	Check(*chainscript.Segment) error
+}
+
+// ContextExpectation is implemented by expectations that need a specific
+// context to validate their segment (for example to trust a certificate).
+type ContextExpectation interface {
+	Expectation
+
+	// Context returns the context used to validate the segment.
+	Context(context.Context, *chainscript.Segment) (context.Context, error)
+}
+
+// Validate decodes and validates the sample's segment. If the expectation
+// has an error code, the segment must be rejected with that code, otherwise
+// it must be valid and pass the expectation's checks.
+func Validate(ctx context.Context, s *Sample, e Expectation) error {
+	if s.ErrorCode != e.ErrorCode() {
+		return errors.Wrapf(ErrErrorCode, "%q instead of %q", s.ErrorCode, e.ErrorCode())
+	}
+
+	segment, err := s.Decode()
+	if err != nil {
+		return err
+	}
+
+	if ce, ok := e.(ContextExpectation); ok {
+		if ctx, err = ce.Context(ctx, segment); err != nil {
+			return err
+		}
+	}
+
+	err = segment.Validate(ctx)
+	if code := e.ErrorCode(); len(code) > 0 {
+		return expectErrorCode(err, code)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return e.Check(segment)
+}
+
+// expectErrorCode checks that the validation error contains a problem with
+// the given code.
+func expectErrorCode(err error, code string) error {
+	if err == nil {
+		return errors.Wrap(ErrSegmentAccepted, code)
+	}
+
+	if verr, ok := err.(*chainscript.ValidationError); ok {
+		for _, p := range verr.Errors() {
+			if p.Code == code {
+				return nil
+			}
+		}
+	} else if chainscript.ErrorCode(err) == code {
+		return nil
+	}
+
+	return errors.Wrapf(ErrSegmentRejected, "expected %s: %s", code, err.Error())
+}
+
+// Result is the outcome of the validation of a sample.
+type Result struct {
+	ID  string
+	Err error
+}
+
+// Run validates samples against the expectation with the same ID.
+// Every expectation must have a sample.
+// Results are sorted by sample ID.
+func Run(ctx context.Context, samples []*Sample, expectations map[string]Expectation) []*Result {
+	var results []*Result
+	found := make(map[string]struct{}, len(samples))
+
+	for _, s := range samples {
+		found[s.ID] = struct{}{}
+
+		e, ok := expectations[s.ID]
+		if !ok {
+			results = append(results, &Result{ID: s.ID, Err: ErrUnknownSample})
+			continue
+		}
+
+		results = append(results, &Result{ID: s.ID, Err: Validate(ctx, s, e)})
+	}
+
+	for id := range expectations {
+		if _, ok := found[id]; !ok {
+			results = append(results, &Result{ID: id, Err: ErrMissingSample})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	return results
+}
@@ -0,0 +1,124 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package samples_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stratumn/go-chainscript/samples"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSample encodes a segment.
+func newSample(t *testing.T, id string, s *chainscript.Segment) *samples.Sample {
+	b, err := chainscript.MarshalSegment(s)
+	require.NoError(t, err)
+
+	return &samples.Sample{ID: id, Data: base64.StdEncoding.EncodeToString(b)}
+}
+
+// checkFunc is an expectation backed by a function.
+type checkFunc struct {
+	code  string
+	check func(*chainscript.Segment) error
+}
+
+func (c *checkFunc) ErrorCode() string { return c.code }
+
+func (c *checkFunc) Check(s *chainscript.Segment) error {
+	if c.check == nil {
+		return nil
+	}
+
+	return c.check(s)
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	valid := newSample(t, "valid", chainscripttest.RandomSegment(t))
+
+	tampered := chainscripttest.RandomSegment(t)
+	tampered.Meta.LinkHash = chainscripttest.RandomHash()
+	invalid := newSample(t, "invalid", tampered)
+	invalid.ErrorCode = chainscript.CodeLinkHashMismatch
+
+	t.Run("valid segment", func(t *testing.T) {
+		assert.NoError(t, samples.Validate(ctx, valid, &checkFunc{}))
+	})
+
+	t.Run("failed check", func(t *testing.T) {
+		errCheck := errors.New("check failed")
+		err := samples.Validate(ctx, valid, &checkFunc{
+			check: func(*chainscript.Segment) error { return errCheck },
+		})
+		assert.Equal(t, errCheck, err)
+	})
+
+	t.Run("expected error code", func(t *testing.T) {
+		err := samples.Validate(ctx, invalid, &checkFunc{code: chainscript.CodeLinkHashMismatch})
+		assert.NoError(t, err)
+	})
+
+	t.Run("different error codes", func(t *testing.T) {
+		err := samples.Validate(ctx, invalid, &checkFunc{code: chainscript.CodeMissingProcess})
+		assert.EqualError(t, errors.Cause(err), samples.ErrErrorCode.Error())
+	})
+
+	t.Run("segment accepted", func(t *testing.T) {
+		s := *valid
+		s.ErrorCode = chainscript.CodeLinkHashMismatch
+		err := samples.Validate(ctx, &s, &checkFunc{code: chainscript.CodeLinkHashMismatch})
+		assert.EqualError(t, errors.Cause(err), samples.ErrSegmentAccepted.Error())
+	})
+
+	t.Run("segment rejected with another code", func(t *testing.T) {
+		s := *invalid
+		s.ErrorCode = chainscript.CodeMissingProcess
+		err := samples.Validate(ctx, &s, &checkFunc{code: chainscript.CodeMissingProcess})
+		assert.EqualError(t, errors.Cause(err), samples.ErrSegmentRejected.Error())
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		err := samples.Validate(ctx, &samples.Sample{ID: "bad", Data: "not base64!"}, &checkFunc{})
+		assert.Error(t, err)
+	})
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	list := []*samples.Sample{
+		newSample(t, "b", chainscripttest.RandomSegment(t)),
+		newSample(t, "a", chainscripttest.RandomSegment(t)),
+		newSample(t, "unknown", chainscripttest.RandomSegment(t)),
+	}
+
+	results := samples.Run(ctx, list, map[string]samples.Expectation{
+		"a":       &checkFunc{},
+		"b":       &checkFunc{},
+		"missing": &checkFunc{},
+	})
+
+	require.Len(t, results, 4)
+	assert.Equal(t, &samples.Result{ID: "a"}, results[0])
+	assert.Equal(t, &samples.Result{ID: "b"}, results[1])
+	assert.Equal(t, &samples.Result{ID: "missing", Err: samples.ErrMissingSample}, results[2])
+	assert.Equal(t, &samples.Result{ID: "unknown", Err: samples.ErrUnknownSample}, results[3])
+}
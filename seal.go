@@ -0,0 +1,219 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Seal errors.
+var (
+	ErrSealedLinkModified = errors.New("sealed link has been modified")
+)
+
+// linkDigest lazily computes and caches a link's hash and signed bytes.
+// It assumes the link isn't modified while it is used.
+type linkDigest struct {
+	link *Link
+
+	mu      sync.Mutex
+	hashed  bool
+	hash    LinkHash
+	hashErr error
+	signed  map[signedBytesKey]signedBytesResult
+}
+
+type signedBytesKey struct {
+	version     string
+	payloadPath string
+}
+
+type signedBytesResult struct {
+	payload []byte
+	err     error
+}
+
+func newLinkDigest(l *Link) *linkDigest {
+	return &linkDigest{link: l}
+}
+
+// linkHash returns the link's hash, computing it on first use.
+func (d *linkDigest) linkHash() (LinkHash, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.hashed {
+		d.hash, d.hashErr = d.link.Hash()
+		d.hashed = true
+	}
+
+	return d.hash, d.hashErr
+}
+
+// signedBytes returns the bytes signed for the given signature version and
+// payload path, computing them on first use.
+func (d *linkDigest) signedBytes(sigVersion, payloadPath string) ([]byte, error) {
+	key := signedBytesKey{version: sigVersion, payloadPath: payloadPath}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if res, ok := d.signed[key]; ok {
+		return res.payload, res.err
+	}
+
+	payload, err := d.link.SignedBytes(sigVersion, payloadPath)
+	if d.signed == nil {
+		d.signed = make(map[signedBytesKey]signedBytesResult)
+	}
+	d.signed[key] = signedBytesResult{payload: payload, err: err}
+
+	return payload, err
+}
+
+// SealedLink is a read-only view of a link that computes its hash and signed
+// bytes only once.
+// The underlying link must not be modified: Hash, SignedBytes and Validate
+// keep using the values of the sealed link. Modifications are only detected
+// on request, by Check.
+type SealedLink struct {
+	digest *linkDigest
+}
+
+// Seal returns a sealed view of the link.
+func (l *Link) Seal() (*SealedLink, error) {
+	lh, err := l.encodedHash()
+	if err != nil {
+		return nil, err
+	}
+
+	d := newLinkDigest(l)
+	d.hash, d.hashed = lh, true
+
+	return &SealedLink{digest: d}, nil
+}
+
+// encodedHash marshals the link once and hashes the resulting bytes.
+func (l *Link) encodedHash() (LinkHash, error) {
+	encoded, err := proto.Marshal(l)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return hashEncodedLink(l.Version, encoded)
+}
+
+// Link returns the sealed link. It must not be modified.
+func (s *SealedLink) Link() *Link {
+	return s.digest.link
+}
+
+// Hash returns the hash of the sealed link.
+func (s *SealedLink) Hash() LinkHash {
+	lh, _ := s.digest.linkHash()
+	return lh
+}
+
+// SignedBytes returns the bytes that should be signed.
+// Results are cached for each signature version and payload path.
+func (s *SealedLink) SignedBytes(sigVersion, payloadPath string) ([]byte, error) {
+	return s.digest.signedBytes(sigVersion, payloadPath)
+}
+
+// Check returns ErrSealedLinkModified if the link has been modified since it
+// was sealed. It marshals the link once and compares its hash to the sealed
+// hash.
+func (s *SealedLink) Check() error {
+	lh, err := s.digest.link.encodedHash()
+	if err != nil || !bytes.Equal(lh, s.digest.hash) {
+		return ErrSealedLinkModified
+	}
+
+	return nil
+}
+
+// Validate validates the link, re-using the cached hash and signed bytes.
+// It doesn't hash the link again: call Check to detect modifications.
+func (s *SealedLink) Validate(ctx context.Context) error {
+	return s.digest.link.validate(ctx, s.digest, time.Time{})
+}
+
+// Segmentify returns a segment from the sealed link.
+func (s *SealedLink) Segmentify() *Segment {
+	return &Segment{
+		Link: s.digest.link,
+		Meta: &SegmentMeta{
+			LinkHash: s.Hash(),
+		},
+	}
+}
+
+// SealedSegment is a segment whose link is sealed.
+// The segment's meta (for example its evidences) can still be modified.
+type SealedSegment struct {
+	segment *Segment
+	link    *SealedLink
+}
+
+// Seal returns a segment whose link is sealed.
+func (s *Segment) Seal() (*SealedSegment, error) {
+	if s.Link == nil {
+		return nil, ErrMissingLink
+	}
+
+	link, err := s.Link.Seal()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SealedSegment{segment: s, link: link}, nil
+}
+
+// Segment returns the underlying segment. Its link must not be modified.
+func (s *SealedSegment) Segment() *Segment {
+	return s.segment
+}
+
+// Link returns the sealed link.
+func (s *SealedSegment) Link() *SealedLink {
+	return s.link
+}
+
+// Check returns ErrSealedLinkModified if the segment's link has been
+// replaced or modified since it was sealed.
+func (s *SealedSegment) Check() error {
+	if s.segment.Link != s.link.Link() {
+		return ErrSealedLinkModified
+	}
+
+	return s.link.Check()
+}
+
+// Validate validates the segment, re-using the cached hash and signed bytes.
+// It returns ErrSealedLinkModified if the segment's link has been replaced,
+// but doesn't hash the link again: call Check to detect modifications.
+func (s *SealedSegment) Validate(ctx context.Context) error {
+	if s.segment.Link != s.link.Link() {
+		return ErrSealedLinkModified
+	}
+
+	return s.segment.validate(ctx, s.link.digest)
+}
@@ -0,0 +1,132 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealedLink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cached values", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithRandomData().WithSignature(t, "").Build()
+		sealed, err := l.Seal()
+		require.NoError(t, err)
+
+		lh, err := l.Hash()
+		require.NoError(t, err)
+		assert.Equal(t, lh, sealed.Hash())
+
+		expected, err := l.SignedBytes(chainscript.SignatureVersion, "[version,meta]")
+		require.NoError(t, err)
+		signed, err := sealed.SignedBytes(chainscript.SignatureVersion, "[version,meta]")
+		require.NoError(t, err)
+		assert.Equal(t, expected, signed)
+
+		assert.NoError(t, sealed.Check())
+		assert.NoError(t, sealed.Validate(ctx))
+		assert.Equal(t, lh, sealed.Segmentify().LinkHash())
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithVersion("0.42.0").Build()
+		_, err := l.Seal()
+		assert.EqualError(t, err, chainscript.ErrUnknownLinkVersion.Error())
+	})
+
+	t.Run("invalid link", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").WithInvalidSignature(t).Build()
+		sealed, err := l.Seal()
+		require.NoError(t, err)
+
+		err = sealed.Validate(ctx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrInvalidSignature.Error())
+	})
+
+	t.Run("modified link", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").Build()
+		sealed, err := l.Seal()
+		require.NoError(t, err)
+
+		lh := sealed.Hash()
+		l.Meta.Step = "tampered"
+
+		assert.EqualError(t, sealed.Check(), chainscript.ErrSealedLinkModified.Error())
+		assert.Equal(t, lh, sealed.Hash())
+	})
+}
+
+func TestSealedSegment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("valid segment", func(t *testing.T) {
+		s := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").Segmentify(t)
+		sealed, err := s.Seal()
+		require.NoError(t, err)
+
+		assert.Equal(t, s, sealed.Segment())
+		assert.Equal(t, s.LinkHash(), sealed.Link().Hash())
+		assert.NoError(t, sealed.Validate(ctx))
+
+		// Evidences aren't part of the sealed link.
+		require.NoError(t, s.AddEvidence(chainscripttest.RandomEvidence(t)))
+		assert.NoError(t, sealed.Validate(ctx))
+	})
+
+	t.Run("missing link", func(t *testing.T) {
+		_, err := (&chainscript.Segment{}).Seal()
+		assert.EqualError(t, err, chainscript.ErrMissingLink.Error())
+	})
+
+	t.Run("link hash mismatch", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		s.Meta.LinkHash = chainscripttest.RandomHash()
+
+		sealed, err := s.Seal()
+		require.NoError(t, err)
+
+		err = sealed.Validate(ctx)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+
+	t.Run("modified link", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		sealed, err := s.Seal()
+		require.NoError(t, err)
+
+		assert.NoError(t, sealed.Check())
+
+		s.Link.Meta.MapId = "tampered"
+		assert.EqualError(t, sealed.Check(), chainscript.ErrSealedLinkModified.Error())
+	})
+
+	t.Run("replaced link", func(t *testing.T) {
+		s := chainscripttest.RandomSegment(t)
+		sealed, err := s.Seal()
+		require.NoError(t, err)
+
+		s.Link = chainscripttest.RandomLink(t)
+		assert.EqualError(t, sealed.Check(), chainscript.ErrSealedLinkModified.Error())
+		assert.EqualError(t, sealed.Validate(ctx), chainscript.ErrSealedLinkModified.Error())
+	})
+}
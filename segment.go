@@ -17,7 +17,10 @@ package chainscript
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 )
 
@@ -31,8 +34,9 @@ var (
 )
 
 // LinkHash returns the link hash.
+// It returns nil if the segment doesn't have metadata.
 func (s *Segment) LinkHash() LinkHash {
-	return s.Meta.LinkHash
+	return s.GetMeta().GetLinkHash()
 }
 
 // SetLinkHash computes and sets the link hash.
@@ -50,28 +54,78 @@ func (s *Segment) SetLinkHash() error {
 	return nil
 }
 
-// Validate checks for errors in a segment
+// Clone returns a deep copy of the segment.
+func (s *Segment) Clone() *Segment {
+	return proto.Clone(s).(*Segment)
+}
+
+// Validate checks for errors in a segment.
+// It reports every problem found in a ValidationError.
 func (s *Segment) Validate(ctx context.Context) error {
 	if s.Link == nil {
-		return ErrMissingLink
+		return s.validate(ctx, nil)
+	}
+
+	return s.validate(ctx, newLinkDigest(s.Link))
+}
+
+// validate checks for errors in a segment, using the digest to compute the
+// link's hash and signed bytes.
+func (s *Segment) validate(ctx context.Context, d *linkDigest) error {
+	verr := &ValidationError{}
+
+	if s.Link == nil {
+		verr.AddError("link", ErrMissingLink)
 	}
 	if s.Meta == nil || len(s.Meta.LinkHash) == 0 {
-		return ErrMissingLinkHash
+		verr.AddError("meta.linkHash", ErrMissingLinkHash)
 	}
 
-	linkHash, err := s.Link.Hash()
-	if err != nil {
-		return err
-	}
+	if s.Link != nil {
+		// Signature keys and certificates are checked at the time of the
+		// oldest trusted evidence (or the current time if there is none).
+		var at time.Time
 
-	if !bytes.Equal(linkHash, s.Meta.LinkHash) {
-		return ErrLinkHashMismatch
+		linkHash, err := d.linkHash()
+		if err == nil && s.Meta != nil && len(s.Meta.LinkHash) > 0 && !bytes.Equal(linkHash, s.Meta.LinkHash) {
+			verr.AddError("meta.linkHash", ErrLinkHashMismatch)
+		}
+
+		if err == nil {
+			at = s.trustedEvidenceTime(ctx, linkHash)
+		}
+
+		s.validateEvidences(verr)
+
+		verr.Merge("link", s.Link.validate(ctx, d, at))
 	}
 
-	return s.Link.Validate(ctx)
+	return verr.ErrorOrNil()
+}
+
+// validateEvidences checks that the segment's evidences are well-formed and
+// that there is at most one evidence per backend and provider.
+func (s *Segment) validateEvidences(verr *ValidationError) {
+	evidences := s.GetMeta().GetEvidences()
+	for i, e := range evidences {
+		field := fmt.Sprintf("meta.evidences[%d]", i)
+		if err := e.Validate(); err != nil {
+			verr.AddError(field, err)
+			continue
+		}
+
+		for _, prev := range evidences[:i] {
+			if prev.Backend == e.Backend && prev.Provider == e.Provider {
+				verr.AddError(field, ErrDuplicateEvidence)
+				break
+			}
+		}
+	}
 }
 
 // AddEvidence adds an evidence to the segment.
+// It returns ErrDuplicateEvidence if the segment already has an evidence from
+// the same backend and provider (see MergeEvidences and UpsertEvidence).
 func (s *Segment) AddEvidence(evidence *Evidence) error {
 	err := evidence.Validate()
 	if err != nil {
@@ -18,6 +18,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
 	"github.com/stratumn/go-chainscript"
 	"github.com/stratumn/go-chainscript/chainscripttest"
 	"github.com/stretchr/testify/assert"
@@ -40,12 +42,27 @@ func TestSegment_LinkHash(t *testing.T) {
 	assert.Equal(t, lh, lhh)
 }
 
+func TestSegment_Clone(t *testing.T) {
+	s := chainscripttest.NewLinkBuilder(t).WithRandomData().WithSignature(t, "").Segmentify(t)
+	require.NoError(t, s.AddEvidence(chainscripttest.RandomEvidence(t)))
+
+	clone := s.Clone()
+	assert.True(t, proto.Equal(s, clone))
+	assert.False(t, s == clone)
+	assert.NoError(t, clone.Validate(context.Background()))
+
+	clone.Meta.Evidences[0].Provider = "other"
+	clone.Link.Meta.MapId = "other"
+	assert.NotEqual(t, s.Meta.Evidences[0].Provider, clone.Meta.Evidences[0].Provider)
+	assert.NotEqual(t, s.Link.Meta.MapId, clone.Link.Meta.MapId)
+}
+
 func TestSegment_Validate(t *testing.T) {
 	t.Run("missing link", func(t *testing.T) {
 		s := &chainscript.Segment{}
 
 		err := s.Validate(context.Background())
-		assert.EqualError(t, err, chainscript.ErrMissingLink.Error())
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrMissingLink.Error())
 	})
 
 	t.Run("missing link hash", func(t *testing.T) {
@@ -53,7 +70,7 @@ func TestSegment_Validate(t *testing.T) {
 		s := &chainscript.Segment{Link: l}
 
 		err := s.Validate(context.Background())
-		assert.EqualError(t, err, chainscript.ErrMissingLinkHash.Error())
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrMissingLinkHash.Error())
 	})
 
 	t.Run("link hash mismatch", func(t *testing.T) {
@@ -66,7 +83,7 @@ func TestSegment_Validate(t *testing.T) {
 		}
 
 		err := s.Validate(context.Background())
-		assert.EqualError(t, err, chainscript.ErrLinkHashMismatch.Error())
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
 	})
 
 	t.Run("invalid link", func(t *testing.T) {
@@ -77,6 +94,19 @@ func TestSegment_Validate(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("invalid evidences", func(t *testing.T) {
+		s, err := chainscripttest.NewLinkBuilder(t).Build().Segmentify()
+		require.NoError(t, err)
+
+		e := chainscripttest.RandomEvidence(t)
+		s.Meta.Evidences = []*chainscript.Evidence{e, e.Clone(), {Version: "1.0.0"}}
+
+		assert.Equal(t, map[string]string{
+			"meta.evidences[1]": chainscript.CodeDuplicateEvidence,
+			"meta.evidences[2]": chainscript.CodeMissingBackend,
+		}, problemFields(t, s.Validate(context.Background())))
+	})
+
 	t.Run("valid segment", func(t *testing.T) {
 		s, err := chainscripttest.NewLinkBuilder(t).Build().Segmentify()
 		require.NoError(t, err)
@@ -15,7 +15,12 @@
 package chainscript
 
 import (
+	"context"
 	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	json "github.com/gibson042/canonicaljson-go"
 	"github.com/jmespath/go-jmespath"
@@ -32,6 +37,14 @@ const (
 	// signature (which uses PEM-encoded private keys).
 	SignatureVersion1_0_0 = "1.0.0"
 
+	// SignatureVersion1_1_0 adds certificate-based signatures.
+	// The signed bytes are computed like in version 1.0.0.
+	// The public key is a PEM-encoded (or concatenated DER) X.509
+	// certificate chain, starting with the signer's certificate.
+	// The signature is produced by the certificate's RSA or ECDSA key over
+	// the SHA-256 hash of the signed bytes (see Link.SignWithCertificate).
+	SignatureVersion1_1_0 = "1.1.0"
+
 	// SignatureVersion is the version used for new signatures.
 	SignatureVersion = SignatureVersion1_0_0
 )
@@ -76,12 +89,17 @@ func (l *Link) Sign(privateKey []byte, payloadPath string) error {
 // The signature version impacts how those bytes are computed.
 func (l *Link) SignedBytes(sigVersion, payloadPath string) ([]byte, error) {
 	switch sigVersion {
-	case SignatureVersion1_0_0:
+	case SignatureVersion1_0_0, SignatureVersion1_1_0:
 		if len(payloadPath) == 0 {
 			payloadPath = "[version,data,meta]"
 		}
 
-		payload, err := jmespath.Search(payloadPath, l)
+		expr, err := compilePayloadPath(payloadPath)
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := expr.Search(l)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -98,9 +116,48 @@ func (l *Link) SignedBytes(sigVersion, payloadPath string) ([]byte, error) {
 	}
 }
 
+// maxCompiledPayloadPaths bounds the cache of compiled payload paths, since
+// payload paths come from (potentially untrusted) signatures.
+const maxCompiledPayloadPaths = 256
+
+var (
+	compiledPayloadPaths     sync.Map
+	compiledPayloadPathCount int32
+)
+
+// compilePayloadPath compiles a JMESPath expression.
+// Parsing is much more expensive than searching, so compiled expressions are
+// cached.
+func compilePayloadPath(payloadPath string) (*jmespath.JMESPath, error) {
+	if expr, ok := compiledPayloadPaths.Load(payloadPath); ok {
+		return expr.(*jmespath.JMESPath), nil
+	}
+
+	expr, err := jmespath.Compile(payloadPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if atomic.LoadInt32(&compiledPayloadPathCount) < maxCompiledPayloadPaths {
+		if _, loaded := compiledPayloadPaths.LoadOrStore(payloadPath, expr); !loaded {
+			atomic.AddInt32(&compiledPayloadPathCount, 1)
+		}
+	}
+
+	return expr, nil
+}
+
 // Validate the signature.
 func (s *Signature) Validate(l *Link) error {
-	signedBytes, err := l.SignedBytes(s.Version, s.PayloadPath)
+	return s.validate(context.Background(), l, newLinkDigest(l), time.Time{})
+}
+
+// validate the signature.
+// Certificates are verified at the given time (the current time given by the
+// context's clock if it is zero).
+// Verifications are de-duplicated if the context contains a signature cache.
+func (s *Signature) validate(ctx context.Context, l *Link, d *linkDigest, at time.Time) error {
+	signedBytes, err := d.signedBytes(s.Version, s.PayloadPath)
 	if err != nil {
 		return err
 	}
@@ -109,20 +166,103 @@ func (s *Signature) Validate(l *Link) error {
 		return err
 	}
 
+	var verify func() error
+
 	switch s.Version {
 	case SignatureVersion1_0_0:
-		sig := signatures.Signature{
-			Message:   signedBytes,
-			PublicKey: s.PublicKey,
-			Signature: s.Signature,
+		verify = func() error {
+			sig := signatures.Signature{
+				Message:   signedBytes,
+				PublicKey: s.PublicKey,
+				Signature: s.Signature,
+			}
+
+			if err := signatures.Verify(&sig); err != nil {
+				return errors.Wrap(ErrInvalidSignature, err.Error())
+			}
+
+			return nil
+		}
+	case SignatureVersion1_1_0:
+		chain, err := parseCertificateChain(s.PublicKey)
+		if err != nil {
+			return err
+		}
+
+		// The chain is verified on every call: the result depends on the
+		// configured roots and the verification time.
+		if err := verifyCertificateChain(ctx, chain, at); err != nil {
+			return err
 		}
 
-		if err := signatures.Verify(&sig); err != nil {
-			return errors.Wrap(ErrInvalidSignature, err.Error())
+		verify = func() error {
+			return verifyCertificateSignature(chain[0], signedBytes, s.Signature)
 		}
 	default:
 		return ErrUnknownSignatureVersion
 	}
 
-	return nil
+	if cache := signatureCacheFromContext(ctx); cache != nil {
+		return cache.verify(s, signedBytes, verify)
+	}
+
+	return verify()
+}
+
+// signatureCache stores the result of signature verifications.
+// It is used to avoid verifying the same signature of the same payload
+// multiple times.
+// It holds at most size results, so that it can't grow beyond the number of
+// signatures it was created for. Signatures are verified without caching
+// once the cache is full.
+type signatureCache struct {
+	mu      sync.RWMutex
+	size    int
+	results map[[sha256.Size]byte]error
+}
+
+func newSignatureCache(size int) *signatureCache {
+	return &signatureCache{size: size, results: make(map[[sha256.Size]byte]error)}
+}
+
+// verify returns the cached verification result of the signature or calls
+// the given verification function.
+func (c *signatureCache) verify(s *Signature, signedBytes []byte, verify func() error) error {
+	h := sha256.New()
+	for _, b := range [][]byte{[]byte(s.Version), signedBytes, s.PublicKey, s.Signature} {
+		// Prefix each part with its length to avoid ambiguous keys.
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(b)))
+		h.Write(size[:])
+		h.Write(b)
+	}
+
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+
+	c.mu.RLock()
+	err, ok := c.results[key]
+	c.mu.RUnlock()
+	if ok {
+		return err
+	}
+
+	err = verify()
+
+	c.mu.Lock()
+	if len(c.results) < c.size {
+		c.results[key] = err
+	}
+	c.mu.Unlock()
+
+	return err
+}
+
+func withSignatureCache(ctx context.Context, c *signatureCache) context.Context {
+	return context.WithValue(ctx, signatureCacheKey, c)
+}
+
+func signatureCacheFromContext(ctx context.Context) *signatureCache {
+	c, _ := ctx.Value(signatureCacheKey).(*signatureCache)
+	return c
 }
@@ -15,6 +15,7 @@
 package chainscript_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -152,6 +153,19 @@ func TestLink_SignedBytes(t *testing.T) {
 
 			assert.NotEqual(t, b1, b2)
 		})
+
+		t.Run("many payload paths", func(t *testing.T) {
+			// Compiled payload paths are cached up to a limit.
+			l := chainscripttest.NewLinkBuilder(t).WithTags("a", "b").Build()
+			expected, err := l.SignedBytes(v1, "meta.tags[0]")
+			require.NoError(t, err)
+
+			for i := 0; i < 300; i++ {
+				b, err := l.SignedBytes(v1, fmt.Sprintf("meta.tags[%d:1]|[0]", -i-2))
+				require.NoError(t, err)
+				assert.Equal(t, expected, b)
+			}
+		})
 	})
 }
 
@@ -0,0 +1,186 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Timeline errors.
+var (
+	ErrEvidenceTimeOrder = errors.New("link has an evidence time before the evidence time of its ancestors")
+)
+
+// CheckEvidenceOrder checks that the evidence time of the segment and of each
+// of its ancestors isn't before the evidence time of an earlier link of the
+// chain. Only evidences trusted by the context are used (see
+// WithEvidenceTrust): links without trusted evidence time are skipped.
+//
+// Evidence times are upper bounds of the creation time of links: a link is
+// known to have been created after its ancestors because it contains their
+// hash, so earlier evidence times usually point to a wrong clock or proof.
+func CheckEvidenceOrder(ctx context.Context, r Resolver, s *Segment) error {
+	if _, err := resolvedLinkHash(s); err != nil {
+		return err
+	}
+
+	ancestors, err := Ancestors(ctx, r, s)
+	if err != nil {
+		return err
+	}
+
+	var latest *Segment
+	var latestTime time.Time
+	for i := len(ancestors) - 1; i >= -1; i-- {
+		current := s
+		if i >= 0 {
+			current = ancestors[i]
+		}
+
+		t := current.trustedEvidenceTime(ctx, current.LinkHash())
+		if t.IsZero() {
+			continue
+		}
+
+		if latest != nil && t.Before(latestTime) {
+			return errors.Wrapf(ErrEvidenceTimeOrder, "%s (%s) before %s (%s)",
+				current.LinkHash(), t.UTC().Format(time.RFC3339),
+				latest.LinkHash(), latestTime.UTC().Format(time.RFC3339))
+		}
+
+		latest, latestTime = current, t
+	}
+
+	return nil
+}
+
+// TimelineOptions configure the timeline of a map.
+type TimelineOptions struct {
+	// MaxGap is the longest expected duration between two consecutive
+	// evidence times. Zero means gaps aren't reported.
+	MaxGap time.Duration
+}
+
+// TimelineEntry is a segment of the timeline.
+type TimelineEntry struct {
+	Segment *Segment
+	// Time of the segment's oldest evidence trusted by the context (see
+	// WithEvidenceTrust). It is zero if the segment doesn't have one.
+	Time time.Time
+}
+
+// TimelineGap is a duration longer than TimelineOptions.MaxGap between two
+// consecutive entries.
+type TimelineGap struct {
+	From     *TimelineEntry
+	To       *TimelineEntry
+	Duration time.Duration
+}
+
+// AnomalyKind is the type of a timeline anomaly.
+type AnomalyKind string
+
+// Timeline anomalies.
+const (
+	// AnomalyMissingTime is reported for segments without a trusted
+	// evidence.
+	AnomalyMissingTime AnomalyKind = "missing_evidence_time"
+	// AnomalyBeforeAncestor is reported for segments whose evidence time is
+	// before the evidence time of their closest timed ancestor.
+	AnomalyBeforeAncestor AnomalyKind = "before_ancestor"
+)
+
+// TimelineAnomaly is an entry that breaks the evidence time ordering.
+type TimelineAnomaly struct {
+	Kind  AnomalyKind
+	Entry *TimelineEntry
+	// Ancestor is the ancestor with a later evidence time for
+	// AnomalyBeforeAncestor.
+	Ancestor *TimelineEntry
+}
+
+// Timeline orders the segments of a map by evidence time.
+type Timeline struct {
+	// Entries are sorted by evidence time. Entries without evidence time are
+	// at the end, in breadth-first order.
+	Entries   []*TimelineEntry
+	Gaps      []*TimelineGap
+	Anomalies []*TimelineAnomaly
+}
+
+// MapTimeline builds the evidence timeline of the given segment and its
+// descendants from the evidences trusted by the context (see
+// WithEvidenceTrust).
+func MapTimeline(ctx context.Context, r ReverseResolver, root *Segment, opts *TimelineOptions) (*Timeline, error) {
+	var maxGap time.Duration
+	if opts != nil {
+		maxGap = opts.MaxGap
+	}
+
+	tl := &Timeline{}
+
+	// timed maps link hashes to their closest timed ancestor or themselves.
+	timed := make(map[string]*TimelineEntry)
+
+	err := Walk(ctx, r, root, &WalkOptions{Edges: EdgeChildren}, func(s *Segment, _ int) error {
+		entry := &TimelineEntry{Segment: s}
+		tl.Entries = append(tl.Entries, entry)
+
+		ancestor := timed[s.Link.PrevLinkHash().String()]
+
+		t := s.trustedEvidenceTime(ctx, s.LinkHash())
+		if t.IsZero() {
+			tl.Anomalies = append(tl.Anomalies, &TimelineAnomaly{Kind: AnomalyMissingTime, Entry: entry})
+			timed[s.LinkHash().String()] = ancestor
+			return nil
+		}
+
+		entry.Time = t
+		timed[s.LinkHash().String()] = entry
+
+		if ancestor != nil && t.Before(ancestor.Time) {
+			tl.Anomalies = append(tl.Anomalies, &TimelineAnomaly{
+				Kind:     AnomalyBeforeAncestor,
+				Entry:    entry,
+				Ancestor: ancestor,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tl.Entries, func(i, j int) bool {
+		ti, tj := tl.Entries[i].Time, tl.Entries[j].Time
+		return !ti.IsZero() && (tj.IsZero() || ti.Before(tj))
+	})
+
+	if maxGap > 0 {
+		for i := 1; i < len(tl.Entries) && !tl.Entries[i].Time.IsZero(); i++ {
+			from, to := tl.Entries[i-1], tl.Entries[i]
+			if d := to.Time.Sub(from.Time); d > maxGap {
+				tl.Gaps = append(tl.Gaps, &TimelineGap{From: from, To: to, Duration: d})
+			}
+		}
+	}
+
+	return tl, nil
+}
@@ -0,0 +1,290 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timedGraph adds evidences to the test graph at the given offsets from a
+// reference time. Segments without offset don't get an evidence.
+func timedGraph(t *testing.T, offsets map[string]time.Duration) (*testGraph, time.Time) {
+	g := newTestGraph(t)
+	start := time.Unix(1500000000, 0)
+
+	for _, s := range []*chainscript.Segment{g.root, g.child1, g.child2, g.grandChild} {
+		if offset, ok := offsets[s.Link.Meta.Step]; ok {
+			require.NoError(t, s.AddEvidence(timeEvidence(start.Add(offset))))
+		}
+	}
+
+	return g, start
+}
+
+// forgedEvidence returns a valid local timestamp of another link hash.
+func forgedEvidence(t *testing.T, at time.Time) *chainscript.Evidence {
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return at })
+	p := chainscript.NewLocalTimestampProvider("forged", chainscripttest.RandomPrivateKey(t))
+
+	other := chainscripttest.RandomHash()
+	evidences, err := p.Submit(ctx, []chainscript.LinkHash{other})
+	require.NoError(t, err)
+
+	return evidences[other.String()]
+}
+
+// untrustedEvidence returns a valid local timestamp of the segment signed by
+// a key that isn't trusted.
+func untrustedEvidence(t *testing.T, s *chainscript.Segment, at time.Time) *chainscript.Evidence {
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return at })
+	p := chainscript.NewLocalTimestampProvider("self", chainscripttest.RandomPrivateKey(t))
+
+	evidences, err := p.Submit(ctx, []chainscript.LinkHash{s.LinkHash()})
+	require.NoError(t, err)
+
+	return evidences[s.LinkHash().String()]
+}
+
+func TestCheckEvidenceOrder(t *testing.T) {
+	ctx := withTimeEvidenceTrust(context.Background())
+
+	t.Run("ordered", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":       0,
+			"child1":     time.Minute,
+			"grandChild": time.Minute,
+		})
+
+		assert.NoError(t, chainscript.CheckEvidenceOrder(ctx, g.resolver(), g.grandChild))
+	})
+
+	t.Run("child before parent", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":       0,
+			"child1":     time.Hour,
+			"grandChild": time.Minute,
+		})
+
+		err := chainscript.CheckEvidenceOrder(ctx, g.resolver(), g.grandChild)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceTimeOrder.Error())
+	})
+
+	t.Run("ancestors out of order", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":   time.Hour,
+			"child1": time.Minute,
+		})
+
+		err := chainscript.CheckEvidenceOrder(ctx, g.resolver(), g.grandChild)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceTimeOrder.Error())
+	})
+
+	t.Run("skips links without evidence time", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":       time.Hour,
+			"grandChild": time.Minute,
+		})
+
+		err := chainscript.CheckEvidenceOrder(ctx, g.resolver(), g.grandChild)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrEvidenceTimeOrder.Error())
+	})
+
+	t.Run("missing ancestor", func(t *testing.T) {
+		g, _ := timedGraph(t, nil)
+		r := chainscript.NewSegmentsResolver(g.child1, g.grandChild)
+
+		err := chainscript.CheckEvidenceOrder(ctx, r, g.grandChild)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrSegmentNotFound.Error())
+	})
+
+	t.Run("forged evidence", func(t *testing.T) {
+		g, start := timedGraph(t, map[string]time.Duration{
+			"root":   0,
+			"child1": time.Minute,
+		})
+		require.NoError(t, g.grandChild.AddEvidence(forgedEvidence(t, start.Add(-time.Hour))))
+
+		assert.NoError(t, chainscript.CheckEvidenceOrder(ctx, g.resolver(), g.grandChild))
+	})
+
+	t.Run("untrusted evidence", func(t *testing.T) {
+		g, start := timedGraph(t, map[string]time.Duration{
+			"root":   0,
+			"child1": time.Hour,
+		})
+		require.NoError(t, g.grandChild.AddEvidence(untrustedEvidence(t, g.grandChild, start.Add(time.Minute))))
+
+		assert.NoError(t, chainscript.CheckEvidenceOrder(ctx, g.resolver(), g.grandChild))
+	})
+
+	t.Run("without evidence trust", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":       0,
+			"child1":     time.Hour,
+			"grandChild": time.Minute,
+		})
+
+		assert.NoError(t, chainscript.CheckEvidenceOrder(context.Background(), g.resolver(), g.grandChild))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		s1, s2 := cycle(t)
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		err := chainscript.CheckEvidenceOrder(ctx, chainscript.NewSegmentsResolver(s1, s2), s1)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+}
+
+func TestMapTimeline(t *testing.T) {
+	ctx := withTimeEvidenceTrust(context.Background())
+
+	t.Run("ordered entries", func(t *testing.T) {
+		g, start := timedGraph(t, map[string]time.Duration{
+			"root":       0,
+			"child1":     2 * time.Minute,
+			"child2":     time.Minute,
+			"grandChild": 3 * time.Minute,
+		})
+
+		tl, err := chainscript.MapTimeline(ctx, g.resolver(), g.root, nil)
+		require.NoError(t, err)
+
+		var segments []*chainscript.Segment
+		for _, e := range tl.Entries {
+			segments = append(segments, e.Segment)
+		}
+
+		assert.Equal(t, []string{"root", "child2", "child1", "grandChild"}, steps(segments))
+		assert.Equal(t, start.Add(time.Minute), tl.Entries[1].Time)
+		assert.Empty(t, tl.Gaps)
+		assert.Empty(t, tl.Anomalies)
+	})
+
+	t.Run("gaps", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":       0,
+			"child1":     time.Minute,
+			"child2":     2 * time.Minute,
+			"grandChild": 3 * time.Hour,
+		})
+
+		tl, err := chainscript.MapTimeline(ctx, g.resolver(), g.root, &chainscript.TimelineOptions{MaxGap: time.Hour})
+		require.NoError(t, err)
+		require.Len(t, tl.Gaps, 1)
+
+		gap := tl.Gaps[0]
+		assert.Equal(t, "child2", gap.From.Segment.Link.Meta.Step)
+		assert.Equal(t, "grandChild", gap.To.Segment.Link.Meta.Step)
+		assert.Equal(t, 3*time.Hour-2*time.Minute, gap.Duration)
+	})
+
+	t.Run("anomalies", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{
+			"root":       time.Hour,
+			"child2":     2 * time.Hour,
+			"grandChild": time.Minute,
+		})
+
+		tl, err := chainscript.MapTimeline(ctx, g.resolver(), g.root, &chainscript.TimelineOptions{MaxGap: time.Minute})
+		require.NoError(t, err)
+
+		assert.Equal(t, "child1", tl.Entries[len(tl.Entries)-1].Segment.Link.Meta.Step)
+		assert.True(t, tl.Entries[len(tl.Entries)-1].Time.IsZero())
+
+		require.Len(t, tl.Anomalies, 2)
+
+		missing := tl.Anomalies[0]
+		assert.Equal(t, chainscript.AnomalyMissingTime, missing.Kind)
+		assert.Equal(t, "child1", missing.Entry.Segment.Link.Meta.Step)
+
+		before := tl.Anomalies[1]
+		assert.Equal(t, chainscript.AnomalyBeforeAncestor, before.Kind)
+		assert.Equal(t, "grandChild", before.Entry.Segment.Link.Meta.Step)
+		assert.Equal(t, "root", before.Ancestor.Segment.Link.Meta.Step)
+
+		assert.Len(t, tl.Gaps, 2, "gaps between timed entries only")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		g, _ := timedGraph(t, nil)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := chainscript.MapTimeline(canceled, g.resolver(), g.root, nil)
+		assert.EqualError(t, err, context.Canceled.Error())
+	})
+
+	t.Run("forged evidence", func(t *testing.T) {
+		g, start := timedGraph(t, map[string]time.Duration{
+			"root":   time.Hour,
+			"child1": 2 * time.Hour,
+			"child2": 2 * time.Hour,
+		})
+		require.NoError(t, g.grandChild.AddEvidence(forgedEvidence(t, start)))
+
+		tl, err := chainscript.MapTimeline(ctx, g.resolver(), g.root, nil)
+		require.NoError(t, err)
+
+		require.Len(t, tl.Anomalies, 1)
+		assert.Equal(t, chainscript.AnomalyMissingTime, tl.Anomalies[0].Kind)
+		assert.Equal(t, "grandChild", tl.Anomalies[0].Entry.Segment.Link.Meta.Step)
+	})
+
+	t.Run("untrusted evidence", func(t *testing.T) {
+		g, start := timedGraph(t, map[string]time.Duration{
+			"root":   0,
+			"child1": time.Minute,
+			"child2": time.Minute,
+		})
+		require.NoError(t, g.grandChild.AddEvidence(untrustedEvidence(t, g.grandChild, start.Add(2*time.Minute))))
+
+		tl, err := chainscript.MapTimeline(ctx, g.resolver(), g.root, nil)
+		require.NoError(t, err)
+
+		require.Len(t, tl.Anomalies, 1)
+		assert.Equal(t, chainscript.AnomalyMissingTime, tl.Anomalies[0].Kind)
+		assert.Equal(t, "grandChild", tl.Anomalies[0].Entry.Segment.Link.Meta.Step)
+		assert.True(t, tl.Anomalies[0].Entry.Time.IsZero())
+	})
+
+	t.Run("unrelated child", func(t *testing.T) {
+		g, _ := timedGraph(t, map[string]time.Duration{"root": 0})
+		r := &lyingResolver{SegmentsResolver: g.resolver(), segments: []*chainscript.Segment{g.audit}}
+
+		_, err := chainscript.MapTimeline(ctx, r, g.root, nil)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUnrelatedSegment.Error())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		s1, s2 := cycle(t)
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		_, err := chainscript.MapTimeline(ctx, chainscript.NewSegmentsResolver(s1, s2), s1, nil)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+}
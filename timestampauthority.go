@@ -0,0 +1,231 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-crypto/signatures"
+)
+
+const (
+	// TimestampAuthorityBackend is the backend of the evidences issued by a
+	// timestamp authority (see Issuer).
+	TimestampAuthorityBackend = "timestamp-authority"
+
+	// TimestampAuthorityVersion is the version of the timestamp authority
+	// proofs.
+	TimestampAuthorityVersion = "1.0.0"
+)
+
+// Timestamp authority errors.
+var (
+	ErrInvalidTimestampProof   = errors.New("timestamp proof signature is invalid")
+	ErrTimestampBackdated      = errors.New("timestamp is before the timestamp of a lower sequence number")
+	ErrTimestampSequenceReused = errors.New("sequence number was issued for different timestamps")
+)
+
+func init() {
+	RegisterProofDecoder(TimestampAuthorityBackend, func(e *Evidence) (Proof, error) {
+		var p TimestampAuthorityProof
+		if err := json.Unmarshal(e.Proof, &p); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return &p, nil
+	})
+}
+
+// TimestampAuthorityProof is a link hash, a time and a sequence number
+// signed by a timestamp authority.
+// Sequence numbers increase with each issued timestamp, so a timestamp that
+// is older than the timestamp of a lower sequence number was backdated (see
+// CheckTimestampSequence).
+// Verify only checks the signature: verifiers should also check that the
+// public key belongs to a trusted authority.
+type TimestampAuthorityProof struct {
+	Timestamp uint64   `json:"timestamp"`
+	Sequence  uint64   `json:"sequence"`
+	LinkHash  LinkHash `json:"linkHash"`
+	PublicKey []byte   `json:"publicKey"`
+	Signature []byte   `json:"signature"`
+}
+
+// Time returns the signed timestamp (UNIX format).
+func (p *TimestampAuthorityProof) Time() uint64 {
+	return p.Timestamp
+}
+
+// Verify checks that the proof signs the given link hash.
+func (p *TimestampAuthorityProof) Verify(linkHash interface{}) bool {
+	return verifyTimestamp(linkHash, p.LinkHash, p.signedBytes(), p.PublicKey, p.Signature)
+}
+
+// signedBytes returns the domain of timestamp authority proofs followed by
+// the big-endian timestamp and sequence number and the link hash.
+func (p *TimestampAuthorityProof) signedBytes() []byte {
+	b := timestampDomain(TimestampAuthorityBackend, TimestampAuthorityVersion)
+	b = append(b, make([]byte, 16)...)
+	binary.BigEndian.PutUint64(b[len(b)-16:], p.Timestamp)
+	binary.BigEndian.PutUint64(b[len(b)-8:], p.Sequence)
+
+	return append(b, p.LinkHash...)
+}
+
+// CheckTimestampSequence checks that the timestamps issued by each authority
+// (identified by its public key) don't decrease when sequence numbers
+// increase, and that sequence numbers aren't reused.
+// Each proof must be signed by its authority, but the authorities' keys
+// aren't checked.
+func CheckTimestampSequence(proofs ...*TimestampAuthorityProof) error {
+	authorities := make(map[string][]*TimestampAuthorityProof)
+	for _, p := range proofs {
+		if !p.Verify(p.LinkHash) {
+			return errors.Wrapf(ErrInvalidTimestampProof, "sequence %d", p.Sequence)
+		}
+
+		key := string(p.PublicKey)
+		authorities[key] = append(authorities[key], p)
+	}
+
+	for _, issued := range authorities {
+		sort.SliceStable(issued, func(i, j int) bool { return issued[i].Sequence < issued[j].Sequence })
+
+		for i := 1; i < len(issued); i++ {
+			prev, p := issued[i-1], issued[i]
+			switch {
+			case p.Sequence == prev.Sequence && (p.Timestamp != prev.Timestamp || !bytes.Equal(p.LinkHash, prev.LinkHash)):
+				return errors.Wrapf(ErrTimestampSequenceReused, "sequence %d", p.Sequence)
+			case p.Timestamp < prev.Timestamp:
+				return errors.Wrapf(ErrTimestampBackdated, "sequence %d (%d) before sequence %d (%d)",
+					p.Sequence, p.Timestamp, prev.Sequence, prev.Timestamp)
+			}
+		}
+	}
+
+	return nil
+}
+
+// IssuerOptions configure an Issuer.
+type IssuerOptions struct {
+	// LastSequence is the last sequence number issued, to resume issuing
+	// after a restart (see Issuer.State).
+	LastSequence uint64
+	// LastTime is the last time issued. Timestamps are never before it,
+	// even if the clock goes back.
+	LastTime time.Time
+}
+
+// Issuer is a timestamp authority that can be embedded in a Go service.
+// It is also an EvidenceProvider, so it can be used with a Batcher.
+// It is safe for concurrent use.
+type Issuer struct {
+	synchronousProvider
+
+	provider   string
+	privateKey []byte
+
+	mu       sync.Mutex
+	sequence uint64
+	last     uint64
+}
+
+// NewIssuer creates a timestamp authority signing with the given key.
+func NewIssuer(provider string, privateKey []byte, opts *IssuerOptions) *Issuer {
+	i := &Issuer{provider: provider, privateKey: privateKey}
+	if opts != nil {
+		i.sequence = opts.LastSequence
+		if !opts.LastTime.IsZero() {
+			i.last = uint64(opts.LastTime.Unix())
+		}
+	}
+
+	return i
+}
+
+// State returns the last issued sequence number and time. Services should
+// persist them and give them to NewIssuer when restarting.
+func (i *Issuer) State() (lastSequence uint64, lastTime time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.sequence, time.Unix(int64(i.last), 0)
+}
+
+// Issue timestamps a link hash with the current time (see WithClock) and the
+// next sequence number.
+func (i *Issuer) Issue(ctx context.Context, linkHash LinkHash) (*Evidence, error) {
+	if err := checkTimestampedLinkHash(linkHash); err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	timestamp := uint64(now(ctx).Unix())
+	if timestamp < i.last {
+		timestamp = i.last
+	}
+
+	p := &TimestampAuthorityProof{
+		Timestamp: timestamp,
+		Sequence:  i.sequence + 1,
+		LinkHash:  linkHash,
+	}
+
+	sig, err := signatures.Sign(i.privateKey, p.signedBytes())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	p.PublicKey, p.Signature = sig.PublicKey, sig.Signature
+
+	proof, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	e, err := NewEvidence(TimestampAuthorityVersion, TimestampAuthorityBackend, i.provider, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	i.sequence, i.last = p.Sequence, timestamp
+	return e, nil
+}
+
+// Backend returns TimestampAuthorityBackend.
+func (i *Issuer) Backend() string {
+	return TimestampAuthorityBackend
+}
+
+// Provider returns the authority's name.
+func (i *Issuer) Provider() string {
+	return i.provider
+}
+
+// Submit timestamps the link hashes.
+func (i *Issuer) Submit(ctx context.Context, linkHashes []LinkHash) (map[string]*Evidence, error) {
+	return submitEach(linkHashes, func(lh LinkHash) (*Evidence, error) {
+		return i.Issue(ctx, lh)
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issue issues a timestamp and decodes its proof.
+func issue(ctx context.Context, t *testing.T, i *chainscript.Issuer, lh chainscript.LinkHash) *chainscript.TimestampAuthorityProof {
+	e, err := i.Issue(ctx, lh)
+	require.NoError(t, err)
+	assert.Equal(t, chainscript.TimestampAuthorityBackend, e.Backend)
+	assert.Equal(t, chainscript.TimestampAuthorityVersion, e.Version)
+
+	proof, err := e.DecodeProof()
+	require.NoError(t, err)
+
+	return proof.(*chainscript.TimestampAuthorityProof)
+}
+
+func TestIssuer(t *testing.T) {
+	clock := time.Unix(1500000000, 0)
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return clock })
+
+	t.Run("issue", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), nil)
+		lh := chainscripttest.RandomHash()
+
+		p := issue(ctx, t, i, lh)
+		assert.Equal(t, uint64(clock.Unix()), p.Time())
+		assert.Equal(t, uint64(1), p.Sequence)
+		assert.True(t, p.Verify(lh))
+		assert.True(t, p.Verify([]byte(lh)))
+		assert.False(t, p.Verify(chainscripttest.RandomHash()))
+
+		lastSequence, lastTime := i.State()
+		assert.Equal(t, uint64(1), lastSequence)
+		assert.Equal(t, clock, lastTime)
+	})
+
+	t.Run("tampered proof", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), nil)
+		lh := chainscripttest.RandomHash()
+		p := issue(ctx, t, i, lh)
+
+		backdated := *p
+		backdated.Timestamp -= 3600
+		assert.False(t, backdated.Verify(lh))
+
+		resequenced := *p
+		resequenced.Sequence++
+		assert.False(t, resequenced.Verify(lh))
+	})
+
+	t.Run("missing link hash", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), nil)
+		_, err := i.Issue(ctx, nil)
+		assert.EqualError(t, err, chainscript.ErrMissingLinkHash.Error())
+	})
+
+	t.Run("invalid link hash size", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), nil)
+		_, err := i.Issue(ctx, chainscripttest.RandomBytes(48))
+		assert.EqualError(t, err, chainscript.ErrInvalidLinkHashSize.Error())
+	})
+
+	t.Run("other backend", func(t *testing.T) {
+		key := chainscripttest.RandomPrivateKey(t)
+		lh := chainscripttest.RandomHash()
+		p := issue(ctx, t, chainscript.NewIssuer("tsa", key, nil), lh)
+
+		// The same key and link hash signed by a local timestamp provider.
+		evidences, err := chainscript.NewLocalTimestampProvider("local", key).Submit(ctx, []chainscript.LinkHash{lh})
+		require.NoError(t, err)
+		local, err := evidences[lh.String()].DecodeProof()
+		require.NoError(t, err)
+
+		p.Signature = local.(*chainscript.LocalTimestampProof).Signature
+		assert.False(t, p.Verify(lh))
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), &chainscript.IssuerOptions{
+			LastSequence: 41,
+			LastTime:     clock.Add(time.Hour),
+		})
+
+		p := issue(ctx, t, i, chainscripttest.RandomHash())
+		assert.Equal(t, uint64(42), p.Sequence)
+		assert.Equal(t, uint64(clock.Add(time.Hour).Unix()), p.Timestamp, "clock went back")
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), nil)
+		evidences := make([]*chainscript.Evidence, 20)
+		errs := make([]error, len(evidences))
+
+		var wg sync.WaitGroup
+		for n := range evidences {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				evidences[n], errs[n] = i.Issue(ctx, chainscripttest.RandomHash())
+			}(n)
+		}
+		wg.Wait()
+
+		var proofs []*chainscript.TimestampAuthorityProof
+		sequences := make(map[uint64]struct{})
+		for n, e := range evidences {
+			require.NoError(t, errs[n])
+			proof, err := e.DecodeProof()
+			require.NoError(t, err)
+
+			p := proof.(*chainscript.TimestampAuthorityProof)
+			proofs = append(proofs, p)
+			sequences[p.Sequence] = struct{}{}
+		}
+		assert.Len(t, sequences, len(proofs))
+		assert.NoError(t, chainscript.CheckTimestampSequence(proofs...))
+	})
+
+	t.Run("batcher", func(t *testing.T) {
+		i := chainscript.NewIssuer("tsa", chainscripttest.RandomPrivateKey(t), nil)
+		b := chainscript.NewBatcher(i, nil)
+
+		s := chainscripttest.RandomSegment(t)
+		require.NoError(t, b.Add(s))
+		require.NoError(t, b.Flush(ctx))
+
+		e := s.GetEvidence(chainscript.TimestampAuthorityBackend, "tsa")
+		require.NotNil(t, e)
+
+		proof, err := e.DecodeProof()
+		require.NoError(t, err)
+		assert.True(t, proof.Verify(s.LinkHash()))
+	})
+}
+
+func TestCheckTimestampSequence(t *testing.T) {
+	clock := time.Unix(1500000000, 0)
+	ctx := chainscript.WithClock(context.Background(), func() time.Time { return clock })
+	key := chainscripttest.RandomPrivateKey(t)
+
+	i := chainscript.NewIssuer("tsa", key, nil)
+	p1 := issue(ctx, t, i, chainscripttest.RandomHash())
+	clock = clock.Add(time.Minute)
+	p2 := issue(ctx, t, i, chainscripttest.RandomHash())
+
+	t.Run("ordered", func(t *testing.T) {
+		assert.NoError(t, chainscript.CheckTimestampSequence(p2, p1, p1))
+	})
+
+	t.Run("backdated", func(t *testing.T) {
+		// An authority reusing its key with an older clock.
+		clock = time.Unix(1400000000, 0)
+		backdated := issue(ctx, t, chainscript.NewIssuer("tsa", key, &chainscript.IssuerOptions{LastSequence: 2}), chainscripttest.RandomHash())
+
+		err := chainscript.CheckTimestampSequence(p1, p2, backdated)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrTimestampBackdated.Error())
+	})
+
+	t.Run("sequence reused", func(t *testing.T) {
+		reused := issue(ctx, t, chainscript.NewIssuer("tsa", key, &chainscript.IssuerOptions{LastSequence: 1}), chainscripttest.RandomHash())
+
+		err := chainscript.CheckTimestampSequence(p1, p2, reused)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrTimestampSequenceReused.Error())
+	})
+
+	t.Run("other authority", func(t *testing.T) {
+		other := issue(ctx, t, chainscript.NewIssuer("other", chainscripttest.RandomPrivateKey(t), nil), chainscripttest.RandomHash())
+		assert.NoError(t, chainscript.CheckTimestampSequence(p1, p2, other))
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		// A forger moving a proof back in time without the authority's key.
+		tampered := *p2
+		tampered.Timestamp = p1.Timestamp - 1
+
+		err := chainscript.CheckTimestampSequence(p1, &tampered)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrInvalidTimestampProof.Error())
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Trust errors.
+var (
+	ErrUntrustedKey = errors.New("signature key is not trusted")
+	ErrKeyRevoked   = errors.New("signature key has been revoked")
+	ErrKeyNotValid  = errors.New("signature key is not valid at the signature time")
+)
+
+// TrustedKey is a public key belonging to a known participant.
+type TrustedKey struct {
+	// PublicKey is the PEM-encoded public key, as found in signatures.
+	PublicKey []byte
+	// Identity of the key's owner.
+	Identity string
+	// NotBefore is the time from which the key is valid.
+	// The zero value means no lower bound.
+	NotBefore time.Time
+	// NotAfter is the time after which the key isn't valid anymore.
+	// The zero value means no upper bound.
+	NotAfter time.Time
+	// RevokedAt is the time at which the key was revoked.
+	// The zero value means the key hasn't been revoked.
+	RevokedAt time.Time
+}
+
+// TrustedAt checks that the key can be trusted at the given time.
+func (k *TrustedKey) TrustedAt(t time.Time) error {
+	if !k.RevokedAt.IsZero() && !t.Before(k.RevokedAt) {
+		return ErrKeyRevoked
+	}
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return ErrKeyNotValid
+	}
+	if !k.NotAfter.IsZero() && t.After(k.NotAfter) {
+		return ErrKeyNotValid
+	}
+
+	return nil
+}
+
+// TrustStore maps public keys to the identities of their owners.
+type TrustStore interface {
+	// Key returns the trusted key matching the given PEM-encoded public key.
+	// It returns ErrUntrustedKey if the key is unknown.
+	Key(ctx context.Context, publicKey []byte) (*TrustedKey, error)
+}
+
+// KeyFingerprint returns a hex-encoded SHA-256 hash of the DER bytes of a
+// PEM-encoded public key. Keys that aren't PEM-encoded are hashed as is.
+func KeyFingerprint(publicKey []byte) string {
+	der := publicKey
+	if block, _ := pem.Decode(publicKey); block != nil {
+		der = block.Bytes
+	}
+
+	h := sha256.Sum256(der)
+	return hex.EncodeToString(h[:])
+}
+
+// MemoryTrustStore is a TrustStore that keeps its keys in memory.
+// It is safe for concurrent use.
+type MemoryTrustStore struct {
+	mu   sync.RWMutex
+	keys map[string]*TrustedKey
+}
+
+// NewMemoryTrustStore creates a trust store containing the given keys.
+func NewMemoryTrustStore(keys ...*TrustedKey) *MemoryTrustStore {
+	s := &MemoryTrustStore{keys: make(map[string]*TrustedKey)}
+	s.Add(keys...)
+	return s
+}
+
+// Add keys to the store, replacing existing keys with the same public key.
+func (s *MemoryTrustStore) Add(keys ...*TrustedKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range keys {
+		s.keys[KeyFingerprint(k.PublicKey)] = k
+	}
+}
+
+// Revoke marks a key as revoked from the given time.
+func (s *MemoryTrustStore) Revoke(publicKey []byte, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[KeyFingerprint(publicKey)]
+	if !ok {
+		return ErrUntrustedKey
+	}
+
+	revoked := *k
+	revoked.RevokedAt = at
+	s.keys[KeyFingerprint(publicKey)] = &revoked
+
+	return nil
+}
+
+// Key returns the trusted key matching the given public key.
+func (s *MemoryTrustStore) Key(_ context.Context, publicKey []byte) (*TrustedKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[KeyFingerprint(publicKey)]
+	if !ok {
+		return nil, ErrUntrustedKey
+	}
+
+	return k, nil
+}
+
+// WithTrustStore returns a context that enables trusted verification.
+// When validating with that context, a signature is only valid if its key is
+// in the trust store and trusted at the time of the segment's oldest trusted
+// evidence (see WithEvidenceTrust). Segments without trusted evidence and
+// links validated on their own are checked against the current time (see
+// WithClock).
+func WithTrustStore(ctx context.Context, s TrustStore) context.Context {
+	return context.WithValue(ctx, trustStoreKey, s)
+}
+
+// TrustStoreFromContext returns the trust store stored in the context, or nil
+// if there is none.
+func TrustStoreFromContext(ctx context.Context) TrustStore {
+	s, _ := ctx.Value(trustStoreKey).(TrustStore)
+	return s
+}
+
+// checkTrust verifies that the keys of the link's valid signatures are
+// trusted at the given time.
+// Certificate-based signatures are skipped: their trust comes from the
+// certificate roots (see WithCertificateRoots).
+func checkTrust(ctx context.Context, s TrustStore, l *Link, at time.Time, verr *ValidationError) {
+	for i, sig := range l.Signatures {
+		if sig.Version == SignatureVersion1_1_0 {
+			continue
+		}
+
+		if err := keyTrustedAt(ctx, s, sig.PublicKey, at); err != nil {
+			verr.AddError(fmt.Sprintf("signatures[%d]", i), err)
+		}
+	}
+}
+
+// keyTrustedAt checks that the key is in the trust store and trusted at the
+// given time.
+func keyTrustedAt(ctx context.Context, s TrustStore, publicKey []byte, at time.Time) error {
+	k, err := s.Key(ctx, publicKey)
+	if err != nil {
+		return err
+	}
+
+	return k.TrustedAt(at)
+}
@@ -0,0 +1,173 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedKey_TrustedAt(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name string
+		key  chainscript.TrustedKey
+		err  error
+	}{{
+		"no bounds",
+		chainscript.TrustedKey{},
+		nil,
+	}, {
+		"within bounds",
+		chainscript.TrustedKey{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+		nil,
+	}, {
+		"not valid yet",
+		chainscript.TrustedKey{NotBefore: now.Add(time.Hour)},
+		chainscript.ErrKeyNotValid,
+	}, {
+		"expired",
+		chainscript.TrustedKey{NotAfter: now.Add(-time.Hour)},
+		chainscript.ErrKeyNotValid,
+	}, {
+		"revoked later",
+		chainscript.TrustedKey{RevokedAt: now.Add(time.Hour)},
+		nil,
+	}, {
+		"revoked",
+		chainscript.TrustedKey{RevokedAt: now},
+		chainscript.ErrKeyRevoked,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.err, tt.key.TrustedAt(now))
+		})
+	}
+}
+
+func TestMemoryTrustStore(t *testing.T) {
+	ctx := context.Background()
+	l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").Build()
+	publicKey := l.Signatures[0].PublicKey
+
+	s := chainscript.NewMemoryTrustStore(&chainscript.TrustedKey{PublicKey: publicKey, Identity: "alice"})
+
+	k, err := s.Key(ctx, publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", k.Identity)
+
+	_, err = s.Key(ctx, []byte("unknown"))
+	assert.EqualError(t, err, chainscript.ErrUntrustedKey.Error())
+
+	revokedAt := time.Now()
+	require.NoError(t, s.Revoke(publicKey, revokedAt))
+	k, err = s.Key(ctx, publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, revokedAt, k.RevokedAt)
+
+	assert.EqualError(t, s.Revoke([]byte("unknown"), revokedAt), chainscript.ErrUntrustedKey.Error())
+}
+
+func TestSegment_Validate_trusted(t *testing.T) {
+	now := time.Now()
+	key := chainscripttest.RandomPrivateKey(t)
+	l := chainscripttest.NewLinkBuilder(t).WithSignatureFromKey(t, key, "").Build()
+	publicKey := l.Signatures[0].PublicKey
+
+	segmentify := func(t *testing.T, evidenceTime time.Time) *chainscript.Segment {
+		s, err := l.Segmentify()
+		require.NoError(t, err)
+		if !evidenceTime.IsZero() {
+			require.NoError(t, s.AddEvidence(timeEvidence(evidenceTime)))
+		}
+		return s
+	}
+
+	validate := func(s *chainscript.Segment, keys ...*chainscript.TrustedKey) error {
+		ctx := chainscript.WithTrustStore(context.Background(), chainscript.NewMemoryTrustStore(keys...))
+		return s.Validate(withTimeEvidenceTrust(ctx))
+	}
+
+	t.Run("trusted key", func(t *testing.T) {
+		err := validate(segmentify(t, time.Time{}), &chainscript.TrustedKey{PublicKey: publicKey})
+		assert.NoError(t, err)
+	})
+
+	t.Run("untrusted key", func(t *testing.T) {
+		err := validate(segmentify(t, time.Time{}))
+		require.Error(t, err)
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrUntrustedKey.Error())
+
+		verr, ok := err.(*chainscript.ValidationError)
+		require.True(t, ok)
+		assert.Equal(t, "link.signatures[0]", verr.Problems[0].Field)
+		assert.Equal(t, chainscript.CodeUntrustedKey, verr.Problems[0].Code)
+	})
+
+	t.Run("revoked after evidence time", func(t *testing.T) {
+		s := segmentify(t, now.Add(-2*time.Hour))
+		err := validate(s, &chainscript.TrustedKey{PublicKey: publicKey, RevokedAt: now.Add(-time.Hour)})
+		assert.NoError(t, err)
+	})
+
+	t.Run("revoked before evidence time", func(t *testing.T) {
+		s := segmentify(t, now.Add(-time.Hour))
+		err := validate(s, &chainscript.TrustedKey{PublicKey: publicKey, RevokedAt: now.Add(-2 * time.Hour)})
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrKeyRevoked.Error())
+	})
+
+	t.Run("expired at evidence time", func(t *testing.T) {
+		s := segmentify(t, now.Add(-time.Hour))
+		err := validate(s, &chainscript.TrustedKey{PublicKey: publicKey, NotAfter: now.Add(-2 * time.Hour)})
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrKeyNotValid.Error())
+	})
+
+	t.Run("revoked without evidence", func(t *testing.T) {
+		s := segmentify(t, time.Time{})
+		err := validate(s, &chainscript.TrustedKey{PublicKey: publicKey, RevokedAt: now.Add(-time.Hour)})
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrKeyRevoked.Error())
+	})
+
+	t.Run("revoked with untrusted evidence", func(t *testing.T) {
+		// Anyone can timestamp a link with their own key: the evidence
+		// doesn't set the validation time.
+		s := segmentify(t, time.Time{})
+		forger := chainscript.NewLocalTimestampProvider("forger", chainscripttest.RandomPrivateKey(t))
+		ctx := chainscript.WithClock(context.Background(), func() time.Time { return now.Add(-2 * time.Hour) })
+		evidences, err := forger.Submit(ctx, []chainscript.LinkHash{s.LinkHash()})
+		require.NoError(t, err)
+		require.NoError(t, s.AddEvidence(evidences[s.LinkHash().String()]))
+
+		err = validate(s, &chainscript.TrustedKey{PublicKey: publicKey, RevokedAt: now.Add(-time.Hour)})
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrKeyRevoked.Error())
+	})
+
+	t.Run("revoked without evidence trust", func(t *testing.T) {
+		s := segmentify(t, now.Add(-2*time.Hour))
+		ts := chainscript.NewMemoryTrustStore(&chainscript.TrustedKey{PublicKey: publicKey, RevokedAt: now.Add(-time.Hour)})
+
+		err := s.Validate(chainscript.WithTrustStore(context.Background(), ts))
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrKeyRevoked.Error())
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trustfile loads a trust store from files.
+//
+// Keys are stored in a PEM bundle. Each PEM block must have a Key-Id header:
+//
+//	-----BEGIN ED25519 PUBLIC KEY-----
+//	Key-Id: alice-2018
+//
+//	MCowBQYDK2VwAyEA...
+//	-----END ED25519 PUBLIC KEY-----
+//
+// The metadata of each key is stored in a YAML file:
+//
+//	keys:
+//	  - id: alice-2018
+//	    identity: alice@example.com
+//	    notBefore: 2018-01-01T00:00:00Z
+//	    notAfter: 2019-01-01T00:00:00Z
+//	    revokedAt: 2018-06-01T00:00:00Z
+//
+// Validity bounds and revocation are optional.
+package trustfile
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// KeyIDHeader is the PEM header containing the key ID.
+const KeyIDHeader = "Key-Id"
+
+// Errors returned when loading a trust store.
+var (
+	ErrMissingKeyID    = errors.New("PEM block doesn't have a Key-Id header")
+	ErrDuplicateKeyID  = errors.New("key ID is used more than once")
+	ErrMissingMetadata = errors.New("key doesn't have metadata")
+	ErrMissingKey      = errors.New("metadata doesn't match any key")
+)
+
+// Metadata describes the keys of a PEM bundle.
+type Metadata struct {
+	Keys []KeyMetadata `yaml:"keys"`
+}
+
+// KeyMetadata describes a key of a PEM bundle.
+type KeyMetadata struct {
+	ID        string    `yaml:"id"`
+	Identity  string    `yaml:"identity"`
+	NotBefore time.Time `yaml:"notBefore"`
+	NotAfter  time.Time `yaml:"notAfter"`
+	RevokedAt time.Time `yaml:"revokedAt"`
+}
+
+// Load reads a PEM bundle and its YAML metadata from files and returns the
+// corresponding trust store.
+func Load(pemPath, metadataPath string) (*chainscript.MemoryTrustStore, error) {
+	bundle, err := ioutil.ReadFile(pemPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	metadata, err := ioutil.ReadFile(metadataPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return Parse(bundle, metadata)
+}
+
+// Parse returns the trust store described by a PEM bundle and its YAML
+// metadata. Every key must have metadata and every metadata entry must
+// match a key.
+func Parse(bundle, metadata []byte) (*chainscript.MemoryTrustStore, error) {
+	var m Metadata
+	if err := yaml.UnmarshalStrict(metadata, &m); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	keys := make(map[string][]byte)
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		id := block.Headers[KeyIDHeader]
+		if len(id) == 0 {
+			return nil, ErrMissingKeyID
+		}
+		if _, ok := keys[id]; ok {
+			return nil, errors.Wrap(ErrDuplicateKeyID, id)
+		}
+
+		// Signatures contain public keys without headers.
+		keys[id] = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes})
+	}
+
+	store := chainscript.NewMemoryTrustStore()
+	seen := make(map[string]struct{})
+
+	for _, km := range m.Keys {
+		publicKey, ok := keys[km.ID]
+		if !ok {
+			return nil, errors.Wrap(ErrMissingKey, km.ID)
+		}
+		if _, ok := seen[km.ID]; ok {
+			return nil, errors.Wrap(ErrDuplicateKeyID, km.ID)
+		}
+		seen[km.ID] = struct{}{}
+
+		store.Add(&chainscript.TrustedKey{
+			PublicKey: publicKey,
+			Identity:  km.Identity,
+			NotBefore: km.NotBefore,
+			NotAfter:  km.NotAfter,
+			RevokedAt: km.RevokedAt,
+		})
+	}
+
+	for id := range keys {
+		if _, ok := seen[id]; !ok {
+			return nil, errors.Wrap(ErrMissingMetadata, id)
+		}
+	}
+
+	return store, nil
+}
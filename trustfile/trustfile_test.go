@@ -0,0 +1,130 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustfile_test
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stratumn/go-chainscript/trustfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// publicKey returns a random PEM-encoded public key, as found in signatures.
+func publicKey(t *testing.T) []byte {
+	l := chainscripttest.NewLinkBuilder(t).WithSignature(t, "").Build()
+	return l.Signatures[0].PublicKey
+}
+
+// withKeyID adds the Key-Id header to a PEM-encoded key.
+func withKeyID(t *testing.T, key []byte, id string) []byte {
+	block, _ := pem.Decode(key)
+	require.NotNil(t, block)
+
+	block.Headers = map[string]string{trustfile.KeyIDHeader: id}
+	return pem.EncodeToMemory(block)
+}
+
+const metadata = `
+keys:
+  - id: alice-2018
+    identity: alice@example.com
+    notBefore: 2018-01-01T00:00:00Z
+    revokedAt: 2018-06-01T00:00:00Z
+  - id: bob
+    identity: bob@example.com
+`
+
+func TestParse(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := publicKey(t), publicKey(t)
+	bundle := append(withKeyID(t, alice, "alice-2018"), withKeyID(t, bob, "bob")...)
+
+	t.Run("valid files", func(t *testing.T) {
+		s, err := trustfile.Parse(bundle, []byte(metadata))
+		require.NoError(t, err)
+
+		k, err := s.Key(ctx, alice)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", k.Identity)
+		assert.Equal(t, time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), k.NotBefore.UTC())
+		assert.Equal(t, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), k.RevokedAt.UTC())
+		assert.True(t, k.NotAfter.IsZero())
+
+		k, err = s.Key(ctx, bob)
+		require.NoError(t, err)
+		assert.Equal(t, "bob@example.com", k.Identity)
+		assert.NoError(t, k.TrustedAt(time.Now()))
+
+		_, err = s.Key(ctx, publicKey(t))
+		assert.EqualError(t, err, chainscript.ErrUntrustedKey.Error())
+	})
+
+	t.Run("missing key ID", func(t *testing.T) {
+		_, err := trustfile.Parse(alice, []byte(metadata))
+		assert.EqualError(t, err, trustfile.ErrMissingKeyID.Error())
+	})
+
+	t.Run("duplicate key ID", func(t *testing.T) {
+		_, err := trustfile.Parse(append(bundle, withKeyID(t, publicKey(t), "bob")...), []byte(metadata))
+		assert.EqualError(t, errors.Cause(err), trustfile.ErrDuplicateKeyID.Error())
+	})
+
+	t.Run("missing metadata", func(t *testing.T) {
+		_, err := trustfile.Parse(append(bundle, withKeyID(t, publicKey(t), "carol")...), []byte(metadata))
+		assert.EqualError(t, errors.Cause(err), trustfile.ErrMissingMetadata.Error())
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		_, err := trustfile.Parse(withKeyID(t, alice, "alice-2018"), []byte(metadata))
+		assert.EqualError(t, errors.Cause(err), trustfile.ErrMissingKey.Error())
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		_, err := trustfile.Parse(bundle, []byte("keys:\n  - unknown: field\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trustfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := publicKey(t)
+	pemPath := filepath.Join(dir, "keys.pem")
+	metadataPath := filepath.Join(dir, "keys.yml")
+	require.NoError(t, ioutil.WriteFile(pemPath, withKeyID(t, key, "bob"), 0600))
+	require.NoError(t, ioutil.WriteFile(metadataPath, []byte("keys:\n  - id: bob\n    identity: bob@example.com\n"), 0600))
+
+	s, err := trustfile.Load(pemPath, metadataPath)
+	require.NoError(t, err)
+
+	k, err := s.Key(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", k.Identity)
+
+	_, err = trustfile.Load(filepath.Join(dir, "missing.pem"), metadataPath)
+	assert.Error(t, err)
+}
@@ -0,0 +1,278 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Severity of a validation problem.
+type Severity int
+
+const (
+	// SeverityError is used for problems that make the validated object
+	// invalid.
+	SeverityError Severity = iota
+	// SeverityWarning is used for suspicious values that don't make the
+	// validated object invalid.
+	SeverityWarning
+)
+
+// String returns a readable representation of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalJSON encodes the severity as a string.
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Error codes returned in validation problems.
+// They are stable and can safely be exposed to API clients.
+const (
+	CodeInvalid                   = "invalid"
+	CodeCertificateKeyMismatch    = "certificate_key_mismatch"
+	CodeCountersignTargetOrder    = "countersign_target_order"
+	CodeDuplicateEvidence         = "duplicate_evidence"
+	CodeInvalidCertificate        = "invalid_certificate"
+	CodeInvalidCountersignTarget  = "invalid_countersign_target"
+	CodeInvalidKeyUsage           = "invalid_key_usage"
+	CodeInvalidPriority           = "invalid_priority"
+	CodeInvalidSignature          = "invalid_signature"
+	CodeKeyNotValid               = "key_not_valid"
+	CodeKeyRevoked                = "key_revoked"
+	CodeLinkHashMismatch          = "link_hash_mismatch"
+	CodeMissingBackend            = "missing_backend"
+	CodeMissingCertificate        = "missing_certificate"
+	CodeMissingCountersignTarget  = "missing_countersign_target"
+	CodeMissingLink               = "missing_link"
+	CodeMissingLinkHash           = "missing_link_hash"
+	CodeMissingMapID              = "missing_map_id"
+	CodeMissingProcess            = "missing_process"
+	CodeMissingProof              = "missing_proof"
+	CodeMissingProvider           = "missing_provider"
+	CodeMissingVersion            = "missing_version"
+	CodeOutDegree                 = "out_degree"
+	CodeSealedLinkModified        = "sealed_link_modified"
+	CodeUnknownClientID           = "unknown_client_id"
+	CodeUnknownLinkVersion        = "unknown_link_version"
+	CodeUncoveredFields           = "uncovered_fields"
+	CodeUnknownSignatureVersion   = "unknown_signature_version"
+	CodeUnsupportedCertificateKey = "unsupported_certificate_key"
+	CodeUnsupportedPayloadPath    = "unsupported_payload_path"
+	CodeUntrustedCertificate      = "untrusted_certificate"
+	CodeUntrustedKey              = "untrusted_key"
+)
+
+// errorCodes maps the package's errors to their code.
+var errorCodes = map[error]string{
+	ErrCertificateKeyMismatch:    CodeCertificateKeyMismatch,
+	ErrCountersignTargetOrder:    CodeCountersignTargetOrder,
+	ErrDuplicateEvidence:         CodeDuplicateEvidence,
+	ErrInvalidCertificate:        CodeInvalidCertificate,
+	ErrInvalidCountersignTarget:  CodeInvalidCountersignTarget,
+	ErrInvalidKeyUsage:           CodeInvalidKeyUsage,
+	ErrInvalidPriority:           CodeInvalidPriority,
+	ErrInvalidSignature:          CodeInvalidSignature,
+	ErrKeyNotValid:               CodeKeyNotValid,
+	ErrKeyRevoked:                CodeKeyRevoked,
+	ErrLinkHashMismatch:          CodeLinkHashMismatch,
+	ErrMissingBackend:            CodeMissingBackend,
+	ErrMissingCertificate:        CodeMissingCertificate,
+	ErrMissingCountersignTarget:  CodeMissingCountersignTarget,
+	ErrMissingLink:               CodeMissingLink,
+	ErrMissingLinkHash:           CodeMissingLinkHash,
+	ErrMissingMapID:              CodeMissingMapID,
+	ErrMissingProcess:            CodeMissingProcess,
+	ErrMissingProof:              CodeMissingProof,
+	ErrMissingProvider:           CodeMissingProvider,
+	ErrMissingVersion:            CodeMissingVersion,
+	ErrOutDegree:                 CodeOutDegree,
+	ErrSealedLinkModified:        CodeSealedLinkModified,
+	ErrUncoveredFields:           CodeUncoveredFields,
+	ErrUnknownClientID:           CodeUnknownClientID,
+	ErrUnknownLinkVersion:        CodeUnknownLinkVersion,
+	ErrUnknownSignatureVersion:   CodeUnknownSignatureVersion,
+	ErrUnsupportedCertificateKey: CodeUnsupportedCertificateKey,
+	ErrUnsupportedPayloadPath:    CodeUnsupportedPayloadPath,
+	ErrUntrustedCertificate:      CodeUntrustedCertificate,
+	ErrUntrustedKey:              CodeUntrustedKey,
+}
+
+// ErrorCode returns the code of the given error.
+// Errors that don't come from this package have the CodeInvalid code.
+func ErrorCode(err error) string {
+	if code, ok := errorCodes[errors.Cause(err)]; ok {
+		return code
+	}
+
+	return CodeInvalid
+}
+
+// ValidationProblem describes a single problem found during validation.
+type ValidationProblem struct {
+	// Path of the invalid field (for example meta.refs[2].linkHash).
+	// It uses the JSON names of the fields and can be empty when the problem
+	// isn't specific to a field.
+	Field    string
+	Code     string
+	Severity Severity
+	Err      error
+}
+
+// Error returns the field and the problem's message.
+func (p *ValidationProblem) Error() string {
+	if len(p.Field) == 0 {
+		return p.Err.Error()
+	}
+
+	return p.Field + ": " + p.Err.Error()
+}
+
+// MarshalJSON encodes the problem in a format suitable for API responses.
+func (p *ValidationProblem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field    string   `json:"field,omitempty"`
+		Code     string   `json:"code"`
+		Severity Severity `json:"severity"`
+		Message  string   `json:"message"`
+	}{
+		Field:    p.Field,
+		Code:     p.Code,
+		Severity: p.Severity,
+		Message:  p.Err.Error(),
+	})
+}
+
+// ValidationError lists every problem found while validating an object.
+// Its cause is the cause of its first error, which lets callers compare it
+// to the package's errors with errors.Cause.
+type ValidationError struct {
+	Problems []*ValidationProblem `json:"problems"`
+}
+
+// AddError records a problem that makes the object invalid.
+func (e *ValidationError) AddError(field string, err error) {
+	e.add(field, SeverityError, err)
+}
+
+// AddWarning records a problem that doesn't make the object invalid.
+func (e *ValidationError) AddWarning(field string, err error) {
+	e.add(field, SeverityWarning, err)
+}
+
+func (e *ValidationError) add(field string, severity Severity, err error) {
+	e.Problems = append(e.Problems, &ValidationProblem{
+		Field:    field,
+		Code:     ErrorCode(err),
+		Severity: severity,
+		Err:      err,
+	})
+}
+
+// Merge records the problems of a nested object's validation error.
+// Their fields are prefixed with the given path.
+// Errors that aren't validation errors are recorded as a single problem.
+func (e *ValidationError) Merge(prefix string, err error) {
+	if err == nil {
+		return
+	}
+
+	nested, ok := err.(*ValidationError)
+	if !ok {
+		e.AddError(prefix, err)
+		return
+	}
+
+	for _, p := range nested.Problems {
+		e.Problems = append(e.Problems, &ValidationProblem{
+			Field:    joinFieldPath(prefix, p.Field),
+			Code:     p.Code,
+			Severity: p.Severity,
+			Err:      p.Err,
+		})
+	}
+}
+
+// joinFieldPath appends a field path to a prefix.
+func joinFieldPath(prefix, field string) string {
+	switch {
+	case len(prefix) == 0:
+		return field
+	case len(field) == 0:
+		return prefix
+	case field[0] == '[':
+		return prefix + field
+	default:
+		return prefix + "." + field
+	}
+}
+
+// Errors returns the problems that make the object invalid.
+func (e *ValidationError) Errors() []*ValidationProblem {
+	var errs []*ValidationProblem
+	for _, p := range e.Problems {
+		if p.Severity == SeverityError {
+			errs = append(errs, p)
+		}
+	}
+
+	return errs
+}
+
+// ErrorOrNil returns the validation error if it contains at least one error
+// problem, and nil otherwise (warnings alone don't make an object invalid).
+func (e *ValidationError) ErrorOrNil() error {
+	if len(e.Errors()) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// Error returns the messages of all the problems.
+func (e *ValidationError) Error() string {
+	var b bytes.Buffer
+	for i, p := range e.Problems {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(p.Error())
+	}
+
+	return b.String()
+}
+
+// Cause returns the cause of the first error problem.
+func (e *ValidationError) Cause() error {
+	errs := e.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errors.Cause(errs[0].Err)
+}
@@ -0,0 +1,138 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problemFields returns the field and code of every problem in a validation
+// error.
+func problemFields(t *testing.T, err error) map[string]string {
+	require.IsType(t, &chainscript.ValidationError{}, err)
+
+	fields := make(map[string]string)
+	for _, p := range err.(*chainscript.ValidationError).Problems {
+		fields[p.Field] = p.Code
+	}
+
+	return fields
+}
+
+func TestValidationError(t *testing.T) {
+	t.Run("link problems", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).
+			WithMapID("").
+			WithSignature(t, "[version,data]").
+			WithInvalidSignature(t).
+			Build()
+		l.Meta.Refs = []*chainscript.LinkReference{
+			&chainscript.LinkReference{Process: "p1", LinkHash: chainscripttest.RandomHash()},
+			&chainscript.LinkReference{Process: "p2"},
+			&chainscript.LinkReference{LinkHash: chainscripttest.RandomHash()},
+		}
+
+		err := l.Validate(context.Background())
+		assert.Equal(t, map[string]string{
+			"meta.mapId":            chainscript.CodeMissingMapID,
+			"meta.refs[1].linkHash": chainscript.CodeMissingLinkHash,
+			"meta.refs[2].process":  chainscript.CodeMissingProcess,
+			"signatures[1]":         chainscript.CodeInvalidSignature,
+		}, problemFields(t, err))
+
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrMissingMapID.Error())
+	})
+
+	t.Run("missing meta", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).WithVersion("").Build()
+		l.Meta = nil
+
+		err := l.Validate(context.Background())
+		assert.Equal(t, map[string]string{
+			"version":           chainscript.CodeMissingVersion,
+			"meta.process.name": chainscript.CodeMissingProcess,
+			"meta.mapId":        chainscript.CodeMissingMapID,
+		}, problemFields(t, err))
+	})
+
+	t.Run("segment problems", func(t *testing.T) {
+		s := chainscripttest.NewLinkBuilder(t).WithProcess("").Segmentify(t)
+		s.Meta.LinkHash = chainscripttest.RandomHash()
+
+		err := s.Validate(context.Background())
+		assert.Equal(t, map[string]string{
+			"meta.linkHash":          chainscript.CodeLinkHashMismatch,
+			"link.meta.process.name": chainscript.CodeMissingProcess,
+		}, problemFields(t, err))
+
+		assert.EqualError(t, errors.Cause(err), chainscript.ErrLinkHashMismatch.Error())
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		errEmptyData := errors.New("data is empty")
+		warnEmptyData := chainscript.ValidatorFunc(func(_ context.Context, l *chainscript.Link) error {
+			verr := &chainscript.ValidationError{}
+			if len(l.Data) == 0 {
+				verr.AddWarning("data", errEmptyData)
+			}
+			return verr
+		})
+		ctx := chainscript.WithValidatorChain(
+			context.Background(),
+			chainscript.NewValidatorChain(warnEmptyData),
+		)
+
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		assert.NoError(t, l.Validate(ctx))
+
+		verr := &chainscript.ValidationError{}
+		verr.AddWarning("data", errEmptyData)
+		assert.NoError(t, verr.ErrorOrNil())
+		assert.Empty(t, verr.Errors())
+
+		verr.AddError("meta.mapId", chainscript.ErrMissingMapID)
+		assert.Equal(t, verr, verr.ErrorOrNil())
+		assert.Len(t, verr.Errors(), 1)
+		assert.Equal(t, chainscript.ErrMissingMapID, errors.Cause(verr))
+		assert.EqualError(t, verr, "data: data is empty; meta.mapId: link map id is missing")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		verr := &chainscript.ValidationError{}
+		verr.AddError("meta.refs[0].linkHash", chainscript.ErrMissingLinkHash)
+		verr.AddWarning("", errors.New("something is odd"))
+
+		b, err := json.Marshal(verr)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"problems":[
+			{"field":"meta.refs[0].linkHash","code":"missing_link_hash","severity":"error","message":"link hash is missing"},
+			{"code":"invalid","severity":"warning","message":"something is odd"}
+		]}`, string(b))
+	})
+}
+
+func TestErrorCode(t *testing.T) {
+	assert.Equal(t, chainscript.CodeMissingProcess, chainscript.ErrorCode(chainscript.ErrMissingProcess))
+	assert.Equal(t, chainscript.CodeInvalidSignature, chainscript.ErrorCode(errors.Wrap(chainscript.ErrInvalidSignature, "bad key")))
+	assert.Equal(t, chainscript.CodeInvalid, chainscript.ErrorCode(errors.New("unknown")))
+}
@@ -0,0 +1,114 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript
+
+import (
+	"context"
+	"sync"
+)
+
+// contextKey is used to store values in a context without collisions with
+// other packages.
+type contextKey int
+
+const (
+	validatorChainKey contextKey = iota
+	signatureCacheKey
+	trustStoreKey
+	certificateRootsKey
+	clockKey
+	evidenceTrustKey
+	supersedeChecksKey
+)
+
+// Validator validates links beyond the structural checks done by
+// Link.Validate.
+// Validators can check signatures against a key directory, data schemas,
+// parent availability or custom business rules. They receive the context
+// given to Link.Validate so they can look up their dependencies (a store,
+// a key directory) from it.
+type Validator interface {
+	Validate(ctx context.Context, l *Link) error
+}
+
+// ValidatorFunc is an adapter to use ordinary functions as validators.
+type ValidatorFunc func(ctx context.Context, l *Link) error
+
+// Validate calls f(ctx, l).
+func (f ValidatorFunc) Validate(ctx context.Context, l *Link) error {
+	return f(ctx, l)
+}
+
+// ValidatorChain runs multiple validators on a link.
+// Validators can apply to every link or only to links of a given process.
+// All validators run even if some of them fail, and their problems are
+// aggregated in the returned ValidationError.
+// Validators can return a ValidationError themselves to report problems on
+// specific fields or warnings.
+// A validator chain is safe for concurrent use.
+type ValidatorChain struct {
+	mu        sync.RWMutex
+	all       []Validator
+	processes map[string][]Validator
+}
+
+// NewValidatorChain creates a validator chain that runs the given
+// validators on every link.
+func NewValidatorChain(validators ...Validator) *ValidatorChain {
+	return &ValidatorChain{
+		all:       validators,
+		processes: make(map[string][]Validator),
+	}
+}
+
+// Register adds validators that only run on links of the given process.
+func (c *ValidatorChain) Register(process string, validators ...Validator) *ValidatorChain {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.processes[process] = append(c.processes[process], validators...)
+	return c
+}
+
+// Validate runs the validators that apply to the link and aggregates their
+// problems.
+func (c *ValidatorChain) Validate(ctx context.Context, l *Link) error {
+	c.mu.RLock()
+	validators := append([]Validator(nil), c.all...)
+	if l.Meta != nil && l.Meta.Process != nil {
+		validators = append(validators, c.processes[l.Meta.Process.Name]...)
+	}
+	c.mu.RUnlock()
+
+	verr := &ValidationError{}
+	for _, v := range validators {
+		verr.Merge("", v.Validate(ctx, l))
+	}
+
+	return verr.ErrorOrNil()
+}
+
+// WithValidatorChain returns a context that makes Link.Validate run the
+// given validator chain after its structural checks.
+func WithValidatorChain(ctx context.Context, c *ValidatorChain) context.Context {
+	return context.WithValue(ctx, validatorChainKey, c)
+}
+
+// ValidatorChainFromContext returns the validator chain stored in the
+// context, or nil if there is none.
+func ValidatorChainFromContext(ctx context.Context) *ValidatorChain {
+	c, _ := ctx.Value(validatorChainKey).(*ValidatorChain)
+	return c
+}
@@ -0,0 +1,136 @@
+// Copyright 2017-2018 Stratumn SAS. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chainscript_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stratumn/go-chainscript"
+	"github.com/stratumn/go-chainscript/chainscripttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type storeKey struct{}
+
+func TestValidatorChain(t *testing.T) {
+	errNoAction := errors.New("action is missing")
+	errNoStep := errors.New("step is missing")
+
+	requireAction := chainscript.ValidatorFunc(func(_ context.Context, l *chainscript.Link) error {
+		if len(l.Meta.Action) == 0 {
+			return errNoAction
+		}
+		return nil
+	})
+
+	requireStep := chainscript.ValidatorFunc(func(_ context.Context, l *chainscript.Link) error {
+		if len(l.Meta.Step) == 0 {
+			return errNoStep
+		}
+		return nil
+	})
+
+	t.Run("no validator in context", func(t *testing.T) {
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		assert.Nil(t, chainscript.ValidatorChainFromContext(context.Background()))
+		assert.NoError(t, l.Validate(context.Background()))
+	})
+
+	t.Run("validators for all processes", func(t *testing.T) {
+		c := chainscript.NewValidatorChain(requireAction)
+		ctx := chainscript.WithValidatorChain(context.Background(), c)
+		assert.Equal(t, c, chainscript.ValidatorChainFromContext(ctx))
+
+		l := chainscripttest.NewLinkBuilder(t).Build()
+		assert.EqualError(t, l.Validate(ctx), errNoAction.Error())
+
+		l = chainscripttest.NewLinkBuilder(t).WithAction("init").Build()
+		assert.NoError(t, l.Validate(ctx))
+	})
+
+	t.Run("validators for a specific process", func(t *testing.T) {
+		c := chainscript.NewValidatorChain().Register("p1", requireStep)
+		ctx := chainscript.WithValidatorChain(context.Background(), c)
+
+		l := chainscripttest.NewLinkBuilder(t).WithProcess("p1").Build()
+		assert.EqualError(t, l.Validate(ctx), errNoStep.Error())
+
+		l = chainscripttest.NewLinkBuilder(t).WithProcess("p2").Build()
+		assert.NoError(t, l.Validate(ctx))
+	})
+
+	t.Run("aggregate errors", func(t *testing.T) {
+		c := chainscript.NewValidatorChain(requireAction).Register("p1", requireStep)
+		ctx := chainscript.WithValidatorChain(context.Background(), c)
+
+		l := chainscripttest.NewLinkBuilder(t).WithProcess("p1").Build()
+		err := l.Validate(ctx)
+		require.IsType(t, &chainscript.ValidationError{}, err)
+
+		problems := err.(*chainscript.ValidationError).Problems
+		require.Len(t, problems, 2)
+		assert.Equal(t, errNoAction, problems[0].Err)
+		assert.Equal(t, errNoStep, problems[1].Err)
+	})
+
+	t.Run("structural errors first", func(t *testing.T) {
+		c := chainscript.NewValidatorChain(requireAction)
+		ctx := chainscript.WithValidatorChain(context.Background(), c)
+
+		l := chainscripttest.NewLinkBuilder(t).WithMapID("").Build()
+		assert.EqualError(t, errors.Cause(l.Validate(ctx)), chainscript.ErrMissingMapID.Error())
+	})
+
+	t.Run("context-aware validator", func(t *testing.T) {
+		parent := chainscripttest.RandomSegment(t)
+		store := map[string]*chainscript.Segment{
+			parent.LinkHash().String(): parent,
+		}
+
+		errParentNotFound := errors.New("parent not found")
+		parentExists := chainscript.ValidatorFunc(func(ctx context.Context, l *chainscript.Link) error {
+			if l.PrevLinkHash() == nil {
+				return nil
+			}
+
+			s := ctx.Value(storeKey{}).(map[string]*chainscript.Segment)
+			if _, ok := s[l.PrevLinkHash().String()]; !ok {
+				return errParentNotFound
+			}
+
+			return nil
+		})
+
+		ctx := context.WithValue(context.Background(), storeKey{}, store)
+		ctx = chainscript.WithValidatorChain(ctx, chainscript.NewValidatorChain(parentExists))
+
+		child := chainscripttest.NewLinkBuilder(t).WithParent(t, parent.Link).Build()
+		assert.NoError(t, child.Validate(ctx))
+
+		orphan := chainscripttest.NewLinkBuilder(t).WithParentHash(chainscripttest.RandomHash()).Build()
+		assert.EqualError(t, orphan.Validate(ctx), errParentNotFound.Error())
+	})
+
+	t.Run("segment validation", func(t *testing.T) {
+		c := chainscript.NewValidatorChain(requireAction)
+		ctx := chainscript.WithValidatorChain(context.Background(), c)
+
+		s := chainscripttest.NewLinkBuilder(t).Segmentify(t)
+		assert.EqualError(t, errors.Cause(s.Validate(ctx)), errNoAction.Error())
+	})
+}